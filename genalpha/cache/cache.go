@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+	"bobik.squidwock.com/root/gal/genalpha/lexer"
+	"bobik.squidwock.com/root/gal/genalpha/parser"
+	"bobik.squidwock.com/root/gal/genalpha/pkg"
+	"bobik.squidwock.com/root/gal/genalpha/utils"
+)
+
+// Disabled turns off reading and writing the cache, files are always lexed and parsed
+var Disabled = os.Getenv("GAL_NO_CACHE") != ""
+
+// the fields of the cached nodes, the node types and the keywords of the lexer
+// are part of the hash, so entries of a gal that parses differently are never
+// decoded into the wrong nodes
+var schema = describeSchema()
+
+type entry struct {
+	Version string
+	Hash    string
+	AST     genalphatypes.ASTNode
+}
+
+// LoadAST returns the syntax tree of the given file, reusing the one cached by a
+// previous run when the contents of the file and the gal version did not change
+func LoadAST(filename string) genalphatypes.ASTNode {
+	contents := utils.ReadContents(filename)
+	if Disabled {
+		return parse(contents)
+	}
+
+	hash := hashContents(contents)
+	cachePath := filepath.Join(pkg.GetCacheDirectory(), hash+".ast")
+
+	ast, ok := read(cachePath, hash)
+	if ok {
+		return ast
+	}
+
+	ast = parse(contents)
+	write(cachePath, entry{
+		Version: genalphatypes.Version,
+		Hash:    hash,
+		AST:     ast,
+	})
+
+	return ast
+}
+
+func parse(contents string) genalphatypes.ASTNode {
+	tokens := lexer.Lex(contents)
+	return parser.Parse(tokens)
+}
+
+// the version is part of the hash so a new gal version never sees old entries
+func hashContents(contents string) string {
+	shaBytes := sha256.Sum256([]byte(genalphatypes.Version + "\x00" + schema + "\x00" + contents))
+	return hex.EncodeToString(shaBytes[:])
+}
+
+func describeSchema() string {
+	var description strings.Builder
+	describeType(&description, reflect.TypeOf(genalphatypes.ASTNode{}), map[reflect.Type]bool{})
+
+	for nodeType := genalphatypes.ASTNodeType(0); nodeType <= genalphatypes.ASTNodeTypeUnknown; nodeType++ {
+		description.WriteString(" " + nodeType.String())
+	}
+	description.WriteString(" " + strings.Join(genalphatypes.Keywords, " "))
+
+	return description.String()
+}
+
+// writes the type with the fields of structs, a struct that was seen before
+// such as the children of a node is only named
+func describeType(description *strings.Builder, t reflect.Type, seen map[reflect.Type]bool) {
+	description.WriteString(t.Kind().String() + " " + t.Name())
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+
+		description.WriteString("{")
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			description.WriteString(field.Name + " ")
+			describeType(description, field.Type, seen)
+			description.WriteString(";")
+		}
+		description.WriteString("}")
+	case reflect.Map:
+		description.WriteString("[")
+		describeType(description, t.Key(), seen)
+		description.WriteString("]")
+		describeType(description, t.Elem(), seen)
+	case reflect.Slice, reflect.Array, reflect.Pointer:
+		description.WriteString(" of ")
+		describeType(description, t.Elem(), seen)
+	}
+}
+
+func read(cachePath string, hash string) (genalphatypes.ASTNode, bool) {
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return genalphatypes.ASTNode{}, false
+	}
+
+	var cached entry
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&cached)
+	if err != nil {
+		return genalphatypes.ASTNode{}, false
+	}
+
+	if cached.Version != genalphatypes.Version || cached.Hash != hash {
+		return genalphatypes.ASTNode{}, false
+	}
+
+	return cached.AST, true
+}
+
+// errors are ignored, the cache is only an optimization
+func write(cachePath string, cached entry) {
+	var data bytes.Buffer
+	err := gob.NewEncoder(&data).Encode(cached)
+	if err != nil {
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(cachePath), 0755)
+	if err != nil {
+		return
+	}
+
+	// write to a temporary file first so a concurrent run never reads half an entry
+	file, err := os.CreateTemp(filepath.Dir(cachePath), "*.tmp")
+	if err != nil {
+		return
+	}
+
+	_, err = file.Write(data.Bytes())
+	file.Close()
+	if err != nil {
+		os.Remove(file.Name())
+		return
+	}
+
+	err = os.Rename(file.Name(), cachePath)
+	if err != nil {
+		os.Remove(file.Name())
+	}
+}
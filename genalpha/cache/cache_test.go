@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+func describe(value any) string {
+	var description strings.Builder
+	describeType(&description, reflect.TypeOf(value), map[reflect.Type]bool{})
+	return description.String()
+}
+
+// a change of the node that gob would decode differently changes the hash
+func TestSchemaChangesWithLayout(t *testing.T) {
+	type node struct {
+		Type     int
+		Children []node
+		Value    string
+	}
+	type nodeWithLine struct {
+		Type     int
+		Children []nodeWithLine
+		Value    string
+		Line     int
+	}
+	type nodeWithStringType struct {
+		Type     string
+		Children []nodeWithStringType
+		Value    string
+	}
+
+	layouts := map[string]bool{}
+	for _, value := range []any{node{}, nodeWithLine{}, nodeWithStringType{}} {
+		layouts[describe(value)] = true
+	}
+	if len(layouts) != 3 {
+		t.Errorf("different layouts have the same description: %v", layouts)
+	}
+
+	if describe(node{}) != describe(node{}) {
+		t.Error("the description of the same layout changed")
+	}
+}
+
+func TestSchemaHasNodeTypesAndKeywords(t *testing.T) {
+	for nodeType := genalphatypes.ASTNodeType(0); nodeType <= genalphatypes.ASTNodeTypeUnknown; nodeType++ {
+		if !strings.Contains(schema, " "+nodeType.String()) {
+			t.Errorf("schema does not name node type %s", nodeType)
+		}
+	}
+
+	for _, keyword := range genalphatypes.Keywords {
+		if !strings.Contains(schema, " "+keyword) {
+			t.Errorf("schema does not have keyword %s", keyword)
+		}
+	}
+}
+
+func TestLoadASTReusesEntry(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+
+	filename := filepath.Join(t.TempDir(), "main.gal")
+	source := "lowkey main{}\n    fire std.println(\"hi\")\nend\n"
+	if err := os.WriteFile(filename, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed := LoadAST(filename)
+	entries, _ := filepath.Glob(filepath.Join(home, ".gal", "cache", "*.ast"))
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 cache entry, got %v", entries)
+	}
+
+	cached := LoadAST(filename)
+	if !reflect.DeepEqual(parsed, cached) {
+		t.Errorf("cached ast differs\nparsed: %+v\ncached: %+v", parsed, cached)
+	}
+
+	// a broken entry is parsed again instead of used
+	if err := os.WriteFile(entries[0], []byte("broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if reparsed := LoadAST(filename); !reflect.DeepEqual(parsed, reparsed) {
+		t.Errorf("broken entry was used: %+v", reparsed)
+	}
+}
@@ -0,0 +1,365 @@
+package checker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+	"bobik.squidwock.com/root/gal/genalpha/cache"
+	"bobik.squidwock.com/root/gal/genalpha/interpreter"
+	"bobik.squidwock.com/root/gal/genalpha/lexer"
+	"bobik.squidwock.com/root/gal/genalpha/parser"
+	"bobik.squidwock.com/root/gal/genalpha/tester"
+)
+
+// Problem is a mistake found in a gal program without running it
+type Problem struct {
+	Filename string
+	Line     int // counting from 1, 0 when the problem is about the whole file
+	Message  string
+}
+
+func (problem Problem) String() string {
+	if problem.Line == 0 {
+		return problem.Filename + ": " + problem.Message
+	}
+
+	return problem.Filename + ":" + strconv.Itoa(problem.Line) + ": " + problem.Message
+}
+
+// Options changes how a program is checked
+type Options struct {
+	// contents used instead of the files on disk, such as unsaved changes in an
+	// editor, keyed by the filename the way imports resolve it
+	Sources map[string]string
+	Library bool // the file is imported by other files and does not need a main function
+}
+
+type file struct {
+	filename string
+	ast      genalphatypes.ASTNode
+}
+
+type checker struct {
+	sources   map[string]string
+	modules   map[string]*interpreter.NativeModule
+	functions map[string]interpreter.Function
+	files     []file
+	loaded    map[string]bool
+
+	// variables declared with fax on the top level of any file, they end up in
+	// the scope main is run in
+	topLevel map[string]bool
+	globals  map[string]bool // GLOBAL_ variables declared anywhere
+
+	problems []Problem
+}
+
+// Check loads the program in filename and everything it imports, the way gal
+// run does, and returns every undefined function and variable, assignment to
+// an undeclared variable, wrong number of arguments and duplicate function
+// declaration it finds, sorted by file and line
+func Check(filename string, modules []*interpreter.NativeModule, options Options) []Problem {
+	c := &checker{
+		sources:   options.Sources,
+		modules:   map[string]*interpreter.NativeModule{},
+		functions: map[string]interpreter.Function{},
+		loaded:    map[string]bool{},
+		topLevel: map[string]bool{
+			"args": true,
+		},
+		globals: map[string]bool{},
+	}
+	for _, module := range modules {
+		c.modules[module.Name] = module
+	}
+
+	filename = interpreter.LoadName(filename)
+	err := c.load(filename)
+	if err != nil {
+		c.report(filename, 0, err.Error())
+		return c.problems
+	}
+
+	if c.functions["main"].Name == "" && !options.Library && !strings.HasSuffix(filename, tester.FileSuffix) {
+		c.report(filename, 0, "no main function declared")
+	}
+
+	for _, file := range c.files {
+		for _, node := range file.ast.Children {
+			if node.Type == genalphatypes.ASTNodeTypeFunctionDeclaration {
+				c.checkFunction(interpreter.NewFunction(node, file.filename))
+				continue
+			}
+			if node.Type == genalphatypes.ASTNodeTypeImport {
+				continue // loaded already
+			}
+
+			// top level statements run in the scope of main while the program is loaded
+			c.checkStatement(node, file.filename, node.Line, c.topLevel)
+		}
+	}
+
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		if c.problems[i].Filename != c.problems[j].Filename {
+			return c.problems[i].Filename < c.problems[j].Filename
+		}
+
+		return c.problems[i].Line < c.problems[j].Line
+	})
+
+	return c.problems
+}
+
+func (c *checker) report(filename string, line int, message string) {
+	c.problems = append(c.problems, Problem{
+		Filename: filename,
+		Line:     line,
+		Message:  message,
+	})
+}
+
+// parses the file and declares its functions and variables, imports are
+// loaded recursively
+func (c *checker) load(filename string) error {
+	if c.loaded[filename] {
+		return nil
+	}
+	c.loaded[filename] = true
+
+	ast, err := c.parse(filename)
+	if err != nil {
+		return err
+	}
+	c.files = append(c.files, file{
+		filename: filename,
+		ast:      ast,
+	})
+
+	for _, node := range ast.Children {
+		switch node.Type {
+		case genalphatypes.ASTNodeTypeFunctionDeclaration:
+			function := interpreter.NewFunction(node, filename)
+			declared := c.functions[function.Name]
+			if declared.Name != "" {
+				c.report(filename, node.Line, "function "+function.Name+" already declared at "+declared.Filename+":"+strconv.Itoa(declared.Line))
+				continue
+			}
+
+			c.functions[function.Name] = function
+			c.declareGlobals(function.Body)
+		case genalphatypes.ASTNodeTypeVariableDeclaration:
+			name := node.Children[0].Value
+			if strings.HasPrefix(name, "GLOBAL_") {
+				c.globals[name] = true
+			} else {
+				c.topLevel[name] = true
+			}
+		case genalphatypes.ASTNodeTypeImport:
+			if len(node.Children) != 1 || node.Children[0].Type != genalphatypes.ASTNodeTypeString {
+				c.report(filename, node.Line, "import should be done with a string argument, the file to import, such as 'gyat \"test.gal\"'")
+				continue
+			}
+
+			importedFilename, err := interpreter.ResolveImport(filename, node.Children[0].Value)
+			if err == nil {
+				err = c.load(importedFilename)
+			}
+			if err != nil {
+				c.report(filename, node.Line, "can not import "+node.Children[0].Value+": "+err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *checker) parse(filename string) (ast genalphatypes.ASTNode, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	source, ok := c.sources[filename]
+	if ok {
+		return parser.Parse(lexer.Lex(source)), nil
+	}
+
+	return cache.LoadAST(filename), nil
+}
+
+func (c *checker) declareGlobals(nodes []genalphatypes.ASTNode) {
+	for _, node := range nodes {
+		if node.Type == genalphatypes.ASTNodeTypeVariableDeclaration && strings.HasPrefix(node.Children[0].Value, "GLOBAL_") {
+			c.globals[node.Children[0].Value] = true
+		}
+
+		c.declareGlobals(node.Children)
+	}
+}
+
+// main and tests are run in the scope the top level statements declared their variables in
+func isEntry(function interpreter.Function) bool {
+	if function.Name == "main" {
+		return true
+	}
+
+	return strings.HasSuffix(function.Filename, tester.FileSuffix) && strings.HasPrefix(function.Name, tester.FunctionPrefix)
+}
+
+func (c *checker) checkFunction(function interpreter.Function) {
+	// blocks do not have their own scope, a variable declared anywhere in the
+	// function can be used anywhere in it
+	scope := map[string]bool{}
+	if isEntry(function) {
+		for name := range c.topLevel {
+			scope[name] = true
+		}
+	}
+	for _, arg := range function.Args {
+		scope[arg.Value] = true
+	}
+	declareLocals(function.Body, scope)
+
+	for _, node := range function.Body {
+		c.checkStatement(node, function.Filename, function.Line, scope)
+	}
+}
+
+func declareLocals(nodes []genalphatypes.ASTNode, scope map[string]bool) {
+	for _, node := range nodes {
+		switch node.Type {
+		case genalphatypes.ASTNodeTypeVariableDeclaration:
+			scope[node.Children[0].Value] = true
+		case genalphatypes.ASTNodeTypeCatch:
+			if node.Value != "" {
+				scope[node.Value] = true
+			}
+		case genalphatypes.ASTNodeTypeIf, genalphatypes.ASTNodeTypeWhile, genalphatypes.ASTNodeTypeTry:
+			declareLocals(node.Children, scope)
+		}
+	}
+}
+
+func (c *checker) declared(name string, scope map[string]bool) bool {
+	return scope[name] || c.globals[name]
+}
+
+func (c *checker) checkStatement(node genalphatypes.ASTNode, filename string, line int, scope map[string]bool) {
+	if node.Line != 0 {
+		line = node.Line
+	}
+
+	switch node.Type {
+	case genalphatypes.ASTNodeTypeVariableDeclaration:
+		c.checkExpression(node.Children[1], filename, line, scope)
+	case genalphatypes.ASTNodeTypeVariableAssignment, genalphatypes.ASTNodeTypeMemberAssignment:
+		name := node.Children[0].Value
+		if !c.declared(name, scope) {
+			c.report(filename, line, "assignment to undeclared variable "+name+", declare it with fax first")
+		}
+
+		for _, child := range node.Children[1:] {
+			c.checkExpression(child, filename, line, scope)
+		}
+	case genalphatypes.ASTNodeTypeIf, genalphatypes.ASTNodeTypeWhile:
+		c.checkExpression(node.Children[0], filename, line, scope)
+		for _, child := range node.Children[1:] {
+			c.checkStatement(child, filename, line, scope)
+		}
+	case genalphatypes.ASTNodeTypeTry:
+		for _, child := range node.Children {
+			c.checkStatement(child, filename, line, scope)
+		}
+	case genalphatypes.ASTNodeTypeReturn:
+		if len(node.Children) != 1 {
+			c.report(filename, line, "return should be done with one argument, the value to return, such as 'rizzult \"returned value\"'")
+			return
+		}
+
+		c.checkExpression(node.Children[0], filename, line, scope)
+	case genalphatypes.ASTNodeTypeThrow:
+		if len(node.Children) != 1 {
+			c.report(filename, line, "yeet should be done with one argument, the error to throw, such as 'yeet \"something went wrong\"'")
+			return
+		}
+
+		c.checkExpression(node.Children[0], filename, line, scope)
+	case genalphatypes.ASTNodeTypeFunctionCall:
+		c.checkCall(node, filename, line, scope)
+	case genalphatypes.ASTNodeTypeImport:
+		// imports in a function are run without the name of the file they are in
+		c.report(filename, line, "imports should be on the top level of the file")
+	}
+}
+
+func (c *checker) checkExpression(node genalphatypes.ASTNode, filename string, line int, scope map[string]bool) {
+	switch node.Type {
+	case genalphatypes.ASTNodeTypeIdentifier:
+		if !c.declared(node.Value, scope) {
+			c.report(filename, line, "undeclared variable "+node.Value)
+		}
+	case genalphatypes.ASTNodeTypeMemberAccess:
+		c.checkExpression(node.Children[0], filename, line, scope)
+		for _, child := range node.Children[1:] {
+			c.checkExpression(child, filename, line, scope)
+		}
+	case genalphatypes.ASTNodeTypeFunctionCall:
+		if node.Line != 0 {
+			line = node.Line
+		}
+
+		c.checkCall(node, filename, line, scope)
+	default:
+		for _, child := range node.Children {
+			c.checkExpression(child, filename, line, scope)
+		}
+	}
+}
+
+func (c *checker) checkCall(node genalphatypes.ASTNode, filename string, line int, scope map[string]bool) {
+	name := node.Children[0].Value
+	args := node.Children[1:]
+	for _, arg := range args {
+		c.checkExpression(arg, filename, line, scope)
+	}
+
+	// fire f() has a single empty argument
+	if len(args) == 1 && args[0].Type == genalphatypes.ASTNodeTypeExpression && len(args[0].Children) == 0 {
+		args = nil
+	}
+
+	function := c.functions[name]
+	if function.Name != "" {
+		if len(args) != len(function.Args) {
+			c.report(filename, line, "function "+name+" takes "+plural(len(function.Args), "argument")+" but is called with "+strconv.Itoa(len(args)))
+		}
+		return
+	}
+
+	i := strings.LastIndex(name, ".")
+	if i == -1 {
+		c.report(filename, line, "undefined function "+name)
+		return
+	}
+
+	module := c.modules[name[:i]]
+	if module == nil {
+		c.report(filename, line, "undefined function "+name+", there is no module "+name[:i])
+		return
+	}
+	if module.Functions[name[i+1:]] == nil {
+		c.report(filename, line, "undefined function "+name+", module "+module.Name+" has no function "+name[i+1:])
+	}
+}
+
+func plural(count int, word string) string {
+	if count == 1 {
+		return "1 " + word
+	}
+
+	return strconv.Itoa(count) + " " + word + "s"
+}
@@ -0,0 +1,85 @@
+package checker
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"bobik.squidwock.com/root/gal/genalpha/interpreter"
+)
+
+const mainSource = `gyat "lib.gal"
+lowkey main{}
+    fax x = 1
+    y = 2
+    fire std.println(z)
+    fire nope()
+    fire helper(1, 2)
+    fire std.nothing(1)
+    fire std.println(x, GLOBAL_count)
+end
+
+lowkey helper{a}
+    rizzult a
+end
+
+lowkey count{}
+    fax GLOBAL_count = 1
+end
+`
+
+const libSource = `lowkey helper{b}
+    rizzult b
+end
+`
+
+func TestCheck(t *testing.T) {
+	sources := map[string]string{
+		"/project/main.gal": mainSource,
+		"/project/lib.gal":  libSource,
+	}
+
+	got := []string{}
+	for _, problem := range Check("/project/main.gal", interpreter.DefaultModules(), Options{Sources: sources}) {
+		got = append(got, problem.String())
+	}
+
+	want := []string{
+		"/project/main.gal:4: assignment to undeclared variable y, declare it with fax first",
+		"/project/main.gal:5: undeclared variable z",
+		"/project/main.gal:6: undefined function nope",
+		"/project/main.gal:7: function helper takes 1 argument but is called with 2",
+		"/project/main.gal:8: undefined function std.nothing, module std has no function nothing",
+		"/project/main.gal:12: function helper already declared at /project/lib.gal:1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCheckMain(t *testing.T) {
+	sources := map[string]string{"/project/lib.gal": libSource}
+
+	problems := Check("/project/lib.gal", interpreter.DefaultModules(), Options{Sources: sources})
+	if len(problems) != 1 || problems[0].String() != "/project/lib.gal: no main function declared" {
+		t.Errorf("expected a missing main function, got %v", problems)
+	}
+
+	problems = Check("/project/lib.gal", interpreter.DefaultModules(), Options{Sources: sources, Library: true})
+	if len(problems) != 0 {
+		t.Errorf("a library does not need main, got %v", problems)
+	}
+}
+
+func TestCheckExamples(t *testing.T) {
+	examples, err := filepath.Glob("../../examples/*.gal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, example := range examples {
+		if problems := Check(example, interpreter.DefaultModules(), Options{}); len(problems) != 0 {
+			t.Errorf("%s: %v", example, problems)
+		}
+	}
+}
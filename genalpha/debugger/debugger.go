@@ -0,0 +1,582 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+	"bobik.squidwock.com/root/gal/genalpha/cache"
+	"bobik.squidwock.com/root/gal/genalpha/interpreter"
+)
+
+// gal programs have a single thread
+const threadID = 1
+
+type stepMode int
+
+const (
+	modeRun stepMode = iota
+	modePause
+	modeStepIn
+	modeStepOver
+	modeStepOut
+)
+
+// Server is a debug adapter that runs a gal program on the ast walker and lets
+// an editor set breakpoints, step through the program and look at its variables
+type Server struct {
+	reader *bufio.Reader
+
+	writer     io.Writer
+	writeMutex sync.Mutex
+	seq        int
+
+	// shared with the goroutine running the program
+	mutex       sync.Mutex
+	breakpoints map[string]map[int]bool // lines by absolute filename
+	mode        stepMode
+	depth       int  // length of the call stack when the step started
+	entry       bool // the next stop is the entry of the program
+
+	launch     *launchArguments
+	configured bool
+
+	// set while the program is paused
+	state      *interpreter.InterpreterState
+	filename   string
+	node       genalphatypes.ASTNode
+	references []map[string]*interpreter.Variable
+	resume     chan struct{}
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		reader:      bufio.NewReader(in),
+		writer:      out,
+		breakpoints: map[string]map[int]bool{},
+		resume:      make(chan struct{}),
+	}
+}
+
+// Serve handles requests until the client disconnects or closes the input
+func (server *Server) Serve() error {
+	for {
+		msg, err := readMessage(server.reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Type != "request" {
+			continue
+		}
+
+		body, err := server.handle(msg)
+		server.respond(msg, body, err)
+
+		switch msg.Command {
+		case "initialize":
+			server.event("initialized", nil)
+		case "launch", "configurationDone":
+			server.start()
+		case "continue", "next", "stepIn", "stepOut":
+			server.wake()
+		case "disconnect", "terminate":
+			return nil
+		}
+	}
+}
+
+func (server *Server) send(msg *message) {
+	server.writeMutex.Lock()
+	defer server.writeMutex.Unlock()
+
+	server.seq++
+	msg.Seq = server.seq
+	writeMessage(server.writer, msg)
+}
+
+func (server *Server) respond(request *message, body any, err error) {
+	success := err == nil
+	response := &message{
+		Type:       "response",
+		Command:    request.Command,
+		RequestSeq: request.Seq,
+		Success:    &success,
+		Body:       body,
+	}
+	if err != nil {
+		response.Message = err.Error()
+	}
+
+	server.send(response)
+}
+
+func (server *Server) event(event string, body any) {
+	server.send(&message{
+		Type:  "event",
+		Event: event,
+		Body:  body,
+	})
+}
+
+func (server *Server) handle(msg *message) (any, error) {
+	switch msg.Command {
+	case "initialize":
+		return map[string]any{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+			"supportsTerminateRequest":         true,
+		}, nil
+	case "launch":
+		var arguments launchArguments
+		if err := json.Unmarshal(msg.Arguments, &arguments); err != nil {
+			return nil, err
+		}
+		if arguments.Program == "" {
+			return nil, fmt.Errorf("missing program to debug")
+		}
+
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		server.launch = &arguments
+		if arguments.StopOnEntry {
+			server.mode = modeStepIn
+			server.entry = true
+		}
+		return nil, nil
+	case "configurationDone":
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		server.configured = true
+		return nil, nil
+	case "setBreakpoints":
+		var arguments setBreakpointsArguments
+		if err := json.Unmarshal(msg.Arguments, &arguments); err != nil {
+			return nil, err
+		}
+
+		return server.setBreakpoints(arguments), nil
+	case "threads":
+		return map[string]any{
+			"threads": []map[string]any{{"id": threadID, "name": "main"}},
+		}, nil
+	case "stackTrace":
+		return server.stackTrace(), nil
+	case "scopes":
+		var arguments struct {
+			FrameID int `json:"frameId"`
+		}
+		if err := json.Unmarshal(msg.Arguments, &arguments); err != nil {
+			return nil, err
+		}
+
+		return server.scopes(arguments.FrameID), nil
+	case "variables":
+		var arguments struct {
+			VariablesReference int `json:"variablesReference"`
+		}
+		if err := json.Unmarshal(msg.Arguments, &arguments); err != nil {
+			return nil, err
+		}
+
+		return server.variables(arguments.VariablesReference), nil
+	case "evaluate":
+		var arguments struct {
+			Expression string `json:"expression"`
+			FrameID    int    `json:"frameId"`
+		}
+		if err := json.Unmarshal(msg.Arguments, &arguments); err != nil {
+			return nil, err
+		}
+
+		return server.evaluate(arguments.Expression, arguments.FrameID)
+	case "continue":
+		server.step(modeRun)
+		return map[string]any{"allThreadsContinued": true}, nil
+	case "next":
+		server.step(modeStepOver)
+		return nil, nil
+	case "stepIn":
+		server.step(modeStepIn)
+		return nil, nil
+	case "stepOut":
+		server.step(modeStepOut)
+		return nil, nil
+	case "pause":
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		server.mode = modePause
+		return nil, nil
+	case "disconnect", "terminate":
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("%s is not supported", msg.Command)
+}
+
+// runs the program once it is launched and the breakpoints are set
+func (server *Server) start() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.launch == nil || !server.configured {
+		return
+	}
+
+	go server.run(*server.launch)
+	server.launch = nil
+}
+
+type outputWriter struct {
+	server   *Server
+	category string
+}
+
+func (writer outputWriter) Write(p []byte) (int, error) {
+	writer.server.event("output", map[string]any{
+		"category": writer.category,
+		"output":   string(p),
+	})
+	return len(p), nil
+}
+
+func (server *Server) run(launch launchArguments) {
+	code := 1
+	defer func() {
+		server.event("exited", map[string]any{"exitCode": code})
+		server.event("terminated", nil)
+	}()
+
+	filename, err := filepath.Abs(launch.Program)
+	if err != nil {
+		fmt.Fprintln(outputWriter{server, "stderr"}, err)
+		return
+	}
+
+	interpreterState := interpreter.NewInterpreter(launch.Args)
+	for _, module := range interpreter.DefaultModules() {
+		interpreterState.RegisterModule(module)
+	}
+	interpreterState.Stdout = interpreter.NewWriterOutput(outputWriter{server, "stdout"})
+	interpreterState.Debugger = server
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		stderr := outputWriter{server, "stderr"}
+		fmt.Fprintln(stderr, r)
+		fmt.Fprint(stderr, interpreter.FormatStackTrace(interpreterState.StackTrace(r)))
+		code = 1
+	}()
+
+	ast := cache.LoadAST(filename)
+	interpreterState.Load(&ast, interpreter.LoadName(filename))
+	code = interpreterState.Run()
+}
+
+// Statement pauses the program when it reaches a breakpoint or finished a step
+func (server *Server) Statement(interpreterState *interpreter.InterpreterState, filename string, node genalphatypes.ASTNode) {
+	server.mutex.Lock()
+
+	depth := len(interpreterState.CallStack)
+	reason := ""
+	switch {
+	case server.entry:
+		reason = "entry"
+		server.entry = false
+	case server.mode == modePause:
+		reason = "pause"
+	case server.mode == modeStepIn,
+		server.mode == modeStepOver && depth <= server.depth,
+		server.mode == modeStepOut && depth < server.depth:
+		reason = "step"
+	case server.breakpoints[filepath.Clean(filename)][node.Line]:
+		reason = "breakpoint"
+	}
+
+	if reason == "" {
+		server.mutex.Unlock()
+		return
+	}
+
+	server.state = interpreterState
+	server.filename = filename
+	server.node = node
+	server.references = nil
+	server.mutex.Unlock()
+
+	server.event("stopped", map[string]any{
+		"reason":            reason,
+		"threadId":          threadID,
+		"allThreadsStopped": true,
+	})
+	<-server.resume
+}
+
+// sets how the program continues, it is resumed after the response is sent
+func (server *Server) step(mode stepMode) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.mode = mode
+	if server.state != nil {
+		server.depth = len(server.state.CallStack)
+	}
+}
+
+// resumes the paused program
+func (server *Server) wake() {
+	server.mutex.Lock()
+	paused := server.state != nil
+	server.state = nil
+	server.mutex.Unlock()
+
+	if paused {
+		server.resume <- struct{}{}
+	}
+}
+
+func (server *Server) setBreakpoints(arguments setBreakpointsArguments) map[string]any {
+	filename, err := filepath.Abs(arguments.Source.Path)
+	if err != nil {
+		filename = arguments.Source.Path
+	}
+
+	statements := statementLines(filename)
+	lines := map[int]bool{}
+	breakpoints := []breakpoint{}
+	for _, requested := range arguments.Breakpoints {
+		lines[requested.Line] = true
+		breakpoints = append(breakpoints, breakpoint{
+			Verified: statements[requested.Line],
+			Line:     requested.Line,
+		})
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.breakpoints[filepath.Clean(filename)] = lines
+
+	return map[string]any{
+		"breakpoints": breakpoints,
+	}
+}
+
+// lines of the file the program can stop at
+func statementLines(filename string) (lines map[int]bool) {
+	lines = map[int]bool{}
+	defer func() {
+		recover()
+	}()
+
+	ast := cache.LoadAST(filename)
+	var walk func(nodes []genalphatypes.ASTNode)
+	walk = func(nodes []genalphatypes.ASTNode) {
+		for _, node := range nodes {
+			switch node.Type {
+			case genalphatypes.ASTNodeTypeFunctionDeclaration:
+				walk(node.Children)
+				continue
+			case genalphatypes.ASTNodeTypeIf, genalphatypes.ASTNodeTypeWhile, genalphatypes.ASTNodeTypeTry:
+				walk(node.Children)
+			case genalphatypes.ASTNodeTypeCatch, genalphatypes.ASTNodeTypeFinally:
+				continue
+			}
+
+			if node.Line != 0 {
+				lines[node.Line] = true
+			}
+		}
+	}
+	walk(ast.Children)
+
+	return lines
+}
+
+func (server *Server) stackTrace() map[string]any {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	frames := []stackFrame{}
+	if server.state == nil {
+		return map[string]any{"stackFrames": frames, "totalFrames": 0}
+	}
+
+	// statements on the top level of a file run while it is loaded, before main
+	callStack := server.state.CallStack
+	if len(callStack) == 0 {
+		callStack = []interpreter.CallFrame{{
+			Function: "<top level>",
+			Filename: server.filename,
+			Line:     server.node.Line,
+		}}
+	}
+
+	for i := len(callStack) - 1; i >= 0; i-- {
+		frame := stackFrame{
+			ID:   i + 1,
+			Name: callStack[i].Function,
+			Source: source{
+				Name: filepath.Base(callStack[i].Filename),
+				Path: callStack[i].Filename,
+			},
+			Line:   callStack[i].Line,
+			Column: 1,
+		}
+		if i == len(callStack)-1 {
+			frame.Column = server.node.Column
+		}
+
+		frames = append(frames, frame)
+	}
+
+	return map[string]any{"stackFrames": frames, "totalFrames": len(frames)}
+}
+
+func (server *Server) frameScope(frameID int) interpreter.Scope {
+	if len(server.state.CallStack) == 0 || frameID < 1 || frameID > len(server.state.CallStack) {
+		return server.state.LocalScope
+	}
+
+	return server.state.FrameScope(frameID - 1)
+}
+
+// variables are looked up by a number the client gets from scopes and from
+// variables with indecies, the numbers are only valid until the program resumes
+func (server *Server) reference(variables map[string]*interpreter.Variable) int {
+	server.references = append(server.references, variables)
+	return len(server.references)
+}
+
+func (server *Server) scopes(frameID int) map[string]any {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	scopes := []scope{}
+	if server.state != nil {
+		scopes = append(scopes, scope{
+			Name:               "Locals",
+			VariablesReference: server.reference(server.frameScope(frameID).Variables),
+		}, scope{
+			Name:               "Globals",
+			VariablesReference: server.reference(server.state.GlobalScope.Variables),
+		})
+	}
+
+	return map[string]any{"scopes": scopes}
+}
+
+func (server *Server) variables(reference int) map[string]any {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	variables := []variable{}
+	if server.state == nil || reference < 1 || reference > len(server.references) {
+		return map[string]any{"variables": variables}
+	}
+
+	values := server.references[reference-1]
+	names := []string{}
+	for name, value := range values {
+		if value != nil {
+			names = append(names, name)
+		}
+	}
+	sortNames(names)
+
+	for _, name := range names {
+		variables = append(variables, server.describe(name, *values[name]))
+	}
+
+	return map[string]any{"variables": variables}
+}
+
+func (server *Server) evaluate(expression string, frameID int) (map[string]any, error) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.state == nil {
+		return nil, fmt.Errorf("the program is not paused")
+	}
+
+	value := server.frameScope(frameID).Variables[expression]
+	if value == nil {
+		value = server.state.GlobalScope.Variables[expression]
+	}
+	if value == nil {
+		return nil, fmt.Errorf("variable %s not found", expression)
+	}
+
+	described := server.describe(expression, *value)
+	return map[string]any{
+		"result":             described.Value,
+		"type":               described.Type,
+		"variablesReference": described.VariablesReference,
+	}, nil
+}
+
+func (server *Server) describe(name string, value interpreter.Variable) variable {
+	described := variable{
+		Name:  name,
+		Value: value.Value,
+		Type:  typeName(value.Type),
+	}
+
+	switch value.Type {
+	case genalphatypes.ASTNodeTypeString:
+		described.Value = strconv.Quote(value.Value)
+	case genalphatypes.ASTNodeTypeNone:
+		described.Value = string(genalphatypes.KeywordNone)
+	}
+
+	if len(value.Indecies) != 0 {
+		described.VariablesReference = server.reference(value.Indecies)
+	}
+
+	return described
+}
+
+func typeName(nodeType genalphatypes.ASTNodeType) string {
+	switch nodeType {
+	case genalphatypes.ASTNodeTypeNumber:
+		return "number"
+	case genalphatypes.ASTNodeTypeString:
+		return "string"
+	case genalphatypes.ASTNodeTypeBoolean:
+		return "boolean"
+	case genalphatypes.ASTNodeTypeNone:
+		return string(genalphatypes.KeywordNone)
+	case genalphatypes.ASTNodeTypeError:
+		return "error"
+	}
+
+	return "unknown"
+}
+
+// numeric indecies in order first, then the rest alphabetically
+func sortNames(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		left, leftErr := strconv.ParseFloat(names[i], 64)
+		right, rightErr := strconv.ParseFloat(names[j], 64)
+		if leftErr == nil && rightErr == nil {
+			return left < right
+		}
+		if (leftErr == nil) != (rightErr == nil) {
+			return leftErr == nil
+		}
+
+		return names[i] < names[j]
+	})
+}
@@ -0,0 +1,304 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const program = `lowkey main{}
+    fax x = 1
+    fax list = args
+    fax y = fire add(x, 2)
+    fire std.println(y)
+end
+
+lowkey add{a b}
+    rizzult a + b
+end
+`
+
+// a client that talks to the server the way an editor does, one request at a
+// time over a pipe
+type client struct {
+	t        *testing.T
+	in       *io.PipeWriter
+	messages chan *message // everything the server wrote, read as it is written like an editor does
+	seq      int
+	done     chan error
+	pending  []*message // events read while waiting for a response
+}
+
+func start(t *testing.T) *client {
+	// the program is parsed through the cache, which lives in the home directory
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	client := &client{
+		t:        t,
+		in:       inWriter,
+		messages: make(chan *message, 100),
+		done:     make(chan error, 1),
+	}
+
+	go func() {
+		client.done <- NewServer(inReader, outWriter).Serve()
+		outWriter.Close()
+	}()
+
+	go func() {
+		defer close(client.messages)
+		reader := bufio.NewReader(outReader)
+		for {
+			msg, err := readMessage(reader)
+			if err != nil {
+				return
+			}
+			client.messages <- msg
+		}
+	}()
+
+	return client
+}
+
+func (client *client) read() *message {
+	client.t.Helper()
+	select {
+	case msg, ok := <-client.messages:
+		if !ok {
+			client.t.Fatal("the server closed the connection")
+		}
+		return msg
+	case <-time.After(10 * time.Second):
+		client.t.Fatal("the server did not answer")
+		return nil
+	}
+}
+
+// sends the request and returns the body of its response
+func (client *client) request(command string, arguments any) json.RawMessage {
+	client.t.Helper()
+	client.seq++
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		client.t.Fatal(err)
+	}
+	err = writeMessage(client.in, &message{Seq: client.seq, Type: "request", Command: command, Arguments: data})
+	if err != nil {
+		client.t.Fatal(err)
+	}
+
+	for {
+		msg := client.read()
+		if msg.Type == "event" {
+			client.pending = append(client.pending, msg)
+			continue
+		}
+
+		if msg.RequestSeq != client.seq || msg.Command != command {
+			client.t.Fatalf("expected the response to %s, got %+v", command, msg)
+		}
+		if msg.Success == nil || !*msg.Success {
+			client.t.Fatalf("%s failed: %s", command, msg.Message)
+		}
+		return mustMarshal(client.t, msg.Body)
+	}
+}
+
+// waits for the event and returns its body, output events are collected in output
+func (client *client) event(event string, output *string) json.RawMessage {
+	client.t.Helper()
+	for {
+		var msg *message
+		if len(client.pending) != 0 {
+			msg, client.pending = client.pending[0], client.pending[1:]
+		} else {
+			msg = client.read()
+		}
+
+		body := mustMarshal(client.t, msg.Body)
+		if msg.Event == event {
+			return body
+		}
+		if msg.Event == "output" && output != nil {
+			var outputBody struct {
+				Output string `json:"output"`
+			}
+			json.Unmarshal(body, &outputBody)
+			*output += outputBody.Output
+		}
+	}
+}
+
+func (client *client) close() {
+	client.request("disconnect", nil)
+	client.in.Close()
+	if err := <-client.done; err != nil {
+		client.t.Error(err)
+	}
+}
+
+func mustMarshal(t *testing.T, value any) json.RawMessage {
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func decode[T any](t *testing.T, data json.RawMessage) T {
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatalf("can not decode %s: %v", data, err)
+	}
+	return value
+}
+
+func writeProgram(t *testing.T) string {
+	filename := filepath.Join(t.TempDir(), "main.gal")
+	if err := os.WriteFile(filename, []byte(program), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+type stopped struct {
+	Reason string `json:"reason"`
+}
+
+func (client *client) topFrame() stackFrame {
+	client.t.Helper()
+	frames := decode[struct {
+		StackFrames []stackFrame `json:"stackFrames"`
+	}](client.t, client.request("stackTrace", map[string]any{"threadId": threadID})).StackFrames
+	if len(frames) == 0 {
+		client.t.Fatal("no stack frames while paused")
+	}
+	return frames[0]
+}
+
+func (client *client) locals(frameID int) map[string]variable {
+	client.t.Helper()
+	scopes := decode[struct {
+		Scopes []scope `json:"scopes"`
+	}](client.t, client.request("scopes", map[string]any{"frameId": frameID})).Scopes
+	if len(scopes) == 0 || scopes[0].Name != "Locals" {
+		client.t.Fatalf("expected locals first, got %+v", scopes)
+	}
+
+	return client.variables(scopes[0].VariablesReference)
+}
+
+func (client *client) variables(reference int) map[string]variable {
+	client.t.Helper()
+	variables := map[string]variable{}
+	for _, v := range decode[struct {
+		Variables []variable `json:"variables"`
+	}](client.t, client.request("variables", map[string]any{"variablesReference": reference})).Variables {
+		variables[v.Name] = v
+	}
+	return variables
+}
+
+func TestBreakpointsAndStepping(t *testing.T) {
+	filename := writeProgram(t)
+	client := start(t)
+	defer client.close()
+
+	client.request("initialize", map[string]any{"adapterID": "gal"})
+	client.event("initialized", nil)
+	client.request("launch", launchArguments{Program: filename, Args: []string{"ten", "twenty"}})
+
+	breakpoints := decode[struct {
+		Breakpoints []breakpoint `json:"breakpoints"`
+	}](t, client.request("setBreakpoints", map[string]any{
+		"source":      source{Path: filename},
+		"breakpoints": []map[string]int{{"line": 4}, {"line": 6}},
+	})).Breakpoints
+	if len(breakpoints) != 2 || !breakpoints[0].Verified || breakpoints[1].Verified {
+		t.Errorf("only the statement on line 4 can stop the program, got %+v", breakpoints)
+	}
+
+	client.request("configurationDone", nil)
+	if reason := decode[stopped](t, client.event("stopped", nil)).Reason; reason != "breakpoint" {
+		t.Errorf("stopped for %s", reason)
+	}
+
+	frame := client.topFrame()
+	if frame.Name != "main" || frame.Line != 4 || frame.Source.Path != filename {
+		t.Errorf("paused in %+v", frame)
+	}
+
+	locals := client.locals(frame.ID)
+	if locals["x"].Value != "1" || locals["x"].Type != "number" {
+		t.Errorf("x is %+v", locals["x"])
+	}
+	if locals["list"].VariablesReference == 0 {
+		t.Fatalf("list has no members: %+v", locals["list"])
+	}
+	if members := client.variables(locals["list"].VariablesReference); members["0"].Value != `"ten"` || members["1"].Value != `"twenty"` {
+		t.Errorf("members of list are %+v", members)
+	}
+
+	evaluated := decode[map[string]any](t, client.request("evaluate", map[string]any{"expression": "x", "frameId": frame.ID}))
+	if evaluated["result"] != "1" {
+		t.Errorf("x evaluates to %v", evaluated)
+	}
+
+	client.request("stepIn", map[string]any{"threadId": threadID})
+	if reason := decode[stopped](t, client.event("stopped", nil)).Reason; reason != "step" {
+		t.Errorf("stopped for %s", reason)
+	}
+	if frame := client.topFrame(); frame.Name != "add" || frame.Line != 9 {
+		t.Errorf("stepped into %+v", frame)
+	}
+
+	client.request("stepOut", map[string]any{"threadId": threadID})
+	client.event("stopped", nil)
+	if frame := client.topFrame(); frame.Name != "main" || frame.Line != 5 {
+		t.Errorf("stepped out to %+v", frame)
+	}
+
+	output := ""
+	client.request("continue", map[string]any{"threadId": threadID})
+	exited := decode[struct {
+		ExitCode int `json:"exitCode"`
+	}](t, client.event("exited", &output))
+	if exited.ExitCode != 0 || output != "3\n" {
+		t.Errorf("exited with %d and output %q", exited.ExitCode, output)
+	}
+	client.event("terminated", nil)
+}
+
+func TestStopOnEntry(t *testing.T) {
+	filename := writeProgram(t)
+	client := start(t)
+	defer client.close()
+
+	client.request("initialize", map[string]any{"adapterID": "gal"})
+	client.request("launch", launchArguments{Program: filename, StopOnEntry: true})
+	client.request("configurationDone", nil)
+
+	if reason := decode[stopped](t, client.event("stopped", nil)).Reason; reason != "entry" {
+		t.Errorf("stopped for %s", reason)
+	}
+	if frame := client.topFrame(); frame.Name != "main" || frame.Line != 2 {
+		t.Errorf("paused in %+v", frame)
+	}
+
+	client.request("next", map[string]any{"threadId": threadID})
+	client.event("stopped", nil)
+	if frame := client.topFrame(); frame.Line != 3 {
+		t.Errorf("next went to %+v", frame)
+	}
+
+	client.request("continue", map[string]any{"threadId": threadID})
+	client.event("terminated", nil)
+}
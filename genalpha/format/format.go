@@ -0,0 +1,368 @@
+package format
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+	"bobik.squidwock.com/root/gal/genalpha/lexer"
+	"bobik.squidwock.com/root/gal/genalpha/parser"
+)
+
+const indent = "    "
+
+// operators made of more than one character, longest first, everything else
+// the lexer emits as an operator is a single character
+var operators = []string{"===", "!==", "==", "!=", ">=", "<=", "&&", "||", "**"}
+
+var blockOpeners = map[string]bool{
+	string(genalphatypes.KeywordFunc):  true,
+	string(genalphatypes.KeywordIf):    true,
+	string(genalphatypes.KeywordWhile): true,
+	string(genalphatypes.KeywordTry):   true,
+}
+
+// keywords that continue the block they are in, they are indented like the opener
+var blockContinuations = map[string]bool{
+	string(genalphatypes.KeywordCatch):   true,
+	string(genalphatypes.KeywordFinally): true,
+}
+
+// keywords that are values, an operator after them is a binary operator
+var valueKeywords = map[string]bool{
+	string(genalphatypes.KeywordTrue):  true,
+	string(genalphatypes.KeywordFalse): true,
+	string(genalphatypes.KeywordNone):  true,
+}
+
+type itemKind int
+
+const (
+	itemWord itemKind = iota // identifiers, numbers, strings and keywords
+	itemOperator
+	itemUnary
+	itemOpen
+	itemClose
+	itemComma
+	itemComment
+)
+
+type item struct {
+	kind  itemKind
+	text  string
+	token genalphatypes.Token
+}
+
+// a line of the output, one statement or a comment
+type line struct {
+	items []item
+	blank bool // an empty line of the source
+}
+
+// Format returns the source in the canonical style: one statement per line,
+// blocks indented by four spaces, single spaces between words and around binary
+// operators and at most one blank line in a row. Comments are kept. Files that
+// do not parse are returned with an error.
+func Format(source string) (formatted string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	tokens := lexer.Lex(source)
+	parser.Parse(tokens)
+
+	sourceLines := strings.Split(source, "\n")
+	for i := range sourceLines {
+		sourceLines[i] = strings.ReplaceAll(sourceLines[i], "\r", "")
+	}
+
+	formatted = render(splitLines(tokens, sourceLines))
+
+	err = compareTokens(tokens, lexer.Lex(formatted))
+	if err != nil {
+		return "", err
+	}
+
+	return formatted, nil
+}
+
+// splits the tokens into lines at newlines and ;, whitespace is dropped
+func splitLines(tokens []genalphatypes.Token, sourceLines []string) []line {
+	lines := []line{}
+	current := line{}
+	previousSeparator := "\n"
+
+	for i, token := range tokens {
+		switch token.Type {
+		case genalphatypes.TokenTypeWhitespace:
+			continue
+		case genalphatypes.TokenTypeNewline:
+			// a ; followed by the end of the line does not make a blank line
+			if len(current.items) == 0 {
+				current.blank = previousSeparator == "\n"
+			}
+			lines = append(lines, current)
+			current = line{}
+			previousSeparator = token.Value
+			continue
+		case genalphatypes.TokenTypeComment:
+			current.items = append(current.items, item{
+				kind:  itemComment,
+				text:  strings.TrimRight(token.Value, " \t"),
+				token: token,
+			})
+		case genalphatypes.TokenTypeOperator:
+			current.items = appendOperator(current.items, token)
+		case genalphatypes.TokenTypePunctuation:
+			kind := itemOpen
+			switch token.Value {
+			case ")", "]", "}":
+				kind = itemClose
+			case ",":
+				kind = itemComma
+			}
+			current.items = append(current.items, item{
+				kind:  kind,
+				text:  token.Value,
+				token: token,
+			})
+		case genalphatypes.TokenTypeString:
+			// strings are written the way they are in the source so escapes are kept
+			end := len(sourceLines[token.Line])
+			if i+1 < len(tokens) && tokens[i+1].Line == token.Line {
+				end = tokens[i+1].Column
+			}
+			current.items = append(current.items, item{
+				kind:  itemWord,
+				text:  strings.TrimRight(sourceLines[token.Line][token.Column:end], " \t"),
+				token: token,
+			})
+		default:
+			current.items = append(current.items, item{
+				kind:  itemWord,
+				text:  token.Value,
+				token: token,
+			})
+		}
+	}
+
+	if len(current.items) != 0 {
+		lines = append(lines, current)
+	}
+
+	return lines
+}
+
+// the lexer emits every operator character on its own, consecutive ones are
+// joined and split again into the operators gal knows
+func appendOperator(items []item, token genalphatypes.Token) []item {
+	text := token.Value
+	for len(items) != 0 && items[len(items)-1].kind == itemOperator {
+		text = items[len(items)-1].text + text
+		items = items[:len(items)-1]
+	}
+
+	for text != "" {
+		operator := text[:1]
+		for _, known := range operators {
+			if strings.HasPrefix(text, known) {
+				operator = known
+				break
+			}
+		}
+
+		items = append(items, item{
+			kind:  itemOperator,
+			text:  operator,
+			token: token,
+		})
+		text = text[len(operator):]
+	}
+
+	return items
+}
+
+func render(lines []line) string {
+	var out strings.Builder
+	level := 0
+	pendingBlank := false
+	afterOpener := true // no blank lines at the start of the file or a block
+
+	for _, line := range lines {
+		if line.blank || len(line.items) == 0 {
+			pendingBlank = !afterOpener
+			continue
+		}
+
+		first := line.items[0]
+		isKeyword := first.kind == itemWord && first.token.Type == genalphatypes.TokenTypeKeyword
+
+		lineLevel := level
+		if isKeyword && first.text == string(genalphatypes.KeywordEnd) {
+			level--
+			lineLevel = level
+		} else if isKeyword && blockContinuations[first.text] {
+			lineLevel = level - 1
+		}
+		if lineLevel < 0 {
+			lineLevel = 0
+		}
+		if level < 0 {
+			level = 0
+		}
+
+		closesBlock := isKeyword && (first.text == string(genalphatypes.KeywordEnd) || blockContinuations[first.text])
+		if pendingBlank && !closesBlock {
+			out.WriteString("\n")
+		}
+		pendingBlank = false
+
+		out.WriteString(strings.Repeat(indent, lineLevel))
+		out.WriteString(renderItems(line.items))
+		out.WriteString("\n")
+
+		afterOpener = isKeyword && (blockOpeners[first.text] || blockContinuations[first.text])
+		if isKeyword && blockOpeners[first.text] {
+			level++
+		}
+	}
+
+	return out.String()
+}
+
+func renderItems(items []item) string {
+	var out strings.Builder
+
+	for i := range items {
+		current := &items[i]
+		if current.kind == itemOperator && isUnary(items[:i], current.text) {
+			current.kind = itemUnary
+		}
+
+		if i != 0 && needsSpace(items[i-1], *current) {
+			out.WriteString(" ")
+		}
+		out.WriteString(current.text)
+	}
+
+	return out.String()
+}
+
+func isUnary(previous []item, operator string) bool {
+	if operator == "!" {
+		return true
+	}
+	if operator != "-" {
+		return false
+	}
+
+	if len(previous) == 0 {
+		return true
+	}
+
+	before := previous[len(previous)-1]
+	switch before.kind {
+	case itemOperator, itemUnary, itemOpen, itemComma:
+		return true
+	case itemWord:
+		return before.token.Type == genalphatypes.TokenTypeKeyword && !valueKeywords[before.text]
+	}
+
+	return false
+}
+
+func needsSpace(previous item, current item) bool {
+	switch {
+	case current.kind == itemComment:
+		return true
+	case current.kind == itemComma, current.kind == itemClose:
+		return false
+	case previous.kind == itemOpen, previous.kind == itemUnary:
+		return false
+	case current.kind == itemOpen && current.text != "[":
+		// calls and function declarations, fire print(...) and lowkey main{}
+		return previous.kind != itemWord || previous.token.Type != genalphatypes.TokenTypeIdentifier
+	case previous.kind == itemClose && current.kind == itemOpen:
+		return false
+	}
+
+	return true
+}
+
+// formatting only changes whitespace, this makes sure of it before anything is written
+func compareTokens(before []genalphatypes.Token, after []genalphatypes.Token) error {
+	before = significantTokens(before)
+	after = significantTokens(after)
+
+	for i := range before {
+		if i >= len(after) {
+			return fmt.Errorf("formatting would remove code after line %d, please report this as a bug", before[i].Line+1)
+		}
+		if before[i].Type != after[i].Type || before[i].Value != after[i].Value {
+			return fmt.Errorf("formatting would change line %d, please report this as a bug", before[i].Line+1)
+		}
+	}
+
+	if len(after) != len(before) {
+		return fmt.Errorf("formatting would add code, please report this as a bug")
+	}
+
+	return nil
+}
+
+func significantTokens(tokens []genalphatypes.Token) []genalphatypes.Token {
+	significant := []genalphatypes.Token{}
+	for _, token := range tokens {
+		switch token.Type {
+		case genalphatypes.TokenTypeWhitespace:
+			continue
+		case genalphatypes.TokenTypeComment:
+			token.Value = strings.TrimRight(token.Value, " \t")
+		case genalphatypes.TokenTypeNewline:
+			if len(significant) == 0 || significant[len(significant)-1].Type == genalphatypes.TokenTypeNewline {
+				continue
+			}
+			token.Value = "\n"
+		}
+		significant = append(significant, token)
+	}
+
+	for len(significant) != 0 && significant[len(significant)-1].Type == genalphatypes.TokenTypeNewline {
+		significant = significant[:len(significant)-1]
+	}
+
+	return significant
+}
+
+// FindFiles returns the gal files at path, which is either a file or a
+// directory that is searched recursively
+func FindFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files := []string{}
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".gal") {
+			files = append(files, file)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
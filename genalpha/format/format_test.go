@@ -0,0 +1,117 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	for name, test := range map[string]struct {
+		source string
+		want   string
+	}{
+		"indents blocks": {
+			source: "lowkey main{}\nforeal yay\nfire std.println(1)\nend\nend\n",
+			want:   "lowkey main{}\n    foreal yay\n        fire std.println(1)\n    end\nend\n",
+		},
+		"spaces operators and arguments": {
+			source: "lowkey main{}\n  fax x=1+2*3\n  fire std.println(x,[1,2],\"a\")\nend\n",
+			want:   "lowkey main{}\n    fax x = 1 + 2 * 3\n    fire std.println(x, [1, 2], \"a\")\nend\n",
+		},
+		"keeps unary operators": {
+			source: "lowkey main{}\n    fax x = 1\n    fire std.println(- x, !yay)\nend\n",
+			want:   "lowkey main{}\n    fax x = 1\n    fire std.println(-x, !yay)\nend\n",
+		},
+		"splits statements at semicolons": {
+			source: "lowkey main{}\n    foreal yay ;fire std.println(1)\n    end\nend\n",
+			want:   "lowkey main{}\n    foreal yay\n        fire std.println(1)\n    end\nend\n",
+		},
+		"indents catch like try": {
+			source: "lowkey main{}\ntryna\nyeet fire std.error(\"a\")\nsus err\nfire std.println(err)\nend\nend\n",
+			want:   "lowkey main{}\n    tryna\n        yeet fire std.error(\"a\")\n    sus err\n        fire std.println(err)\n    end\nend\n",
+		},
+		"keeps comments and one blank line": {
+			source: "` greets\nlowkey main{}\n  fax x = 1   ` one\n\n\n\n  fire std.println(x)\nend\n",
+			want:   "` greets\nlowkey main{}\n    fax x = 1 ` one\n\n    fire std.println(x)\nend\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := Format(test.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+
+			again, err := Format(got)
+			if err != nil || again != got {
+				t.Errorf("formatting again changed the source\n%s\nerror: %v", again, err)
+			}
+		})
+	}
+}
+
+func TestFormatRejectsInvalidSource(t *testing.T) {
+	for _, source := range []string{
+		"lowkey main{}\n    fax x = \"open\nend\n",
+		"lowkey main{}\n    foreal yay\nend\n",
+	} {
+		if formatted, err := Format(source); err == nil {
+			t.Errorf("expected an error for %q, got %q", source, formatted)
+		}
+	}
+}
+
+// formatting a formatted example does not change it
+func TestFormatExamples(t *testing.T) {
+	examples, err := filepath.Glob("../../examples/*.gal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, example := range examples {
+		contents, err := os.ReadFile(example)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, err := Format(string(contents))
+		if err != nil {
+			t.Errorf("%s: %v", example, err)
+			continue
+		}
+
+		again, err := Format(formatted)
+		if err != nil || again != formatted {
+			t.Errorf("%s changed when formatted again\n%s\nerror: %v", example, again, err)
+		}
+	}
+}
+
+func TestFindFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.gal", "notes.txt", filepath.Join("lib", "helper.gal")} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := FindFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0] != filepath.Join(dir, "lib", "helper.gal") || files[1] != filepath.Join(dir, "main.gal") {
+		t.Errorf("got %v", files)
+	}
+
+	single := filepath.Join(dir, "notes.txt")
+	if files, err := FindFiles(single); err != nil || len(files) != 1 || files[0] != single {
+		t.Errorf("a file is its own result, got %v %v", files, err)
+	}
+}
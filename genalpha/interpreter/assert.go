@@ -0,0 +1,194 @@
+package interpreter
+
+import (
+	"strconv"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// TestModule returns the test module with the assertions used by gal test,
+// failed assertions throw an error of kind "assertion"
+func TestModule() *NativeModule {
+	return &NativeModule{
+		Name: "test",
+		Functions: map[string]NativeFunction{
+			"assert": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 2); err != nil {
+					return Variable{}, err
+				}
+				message, err := messageArgument(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				if args[0].Type != genalphatypes.ASTNodeTypeBoolean || args[0].Value != string(genalphatypes.KeywordTrue) {
+					return Variable{}, assertionError(message, "expected yay, got "+formatValue(args[0]))
+				}
+
+				return noneVariable(), nil
+			},
+			"assert_eq": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 2, 3); err != nil {
+					return Variable{}, err
+				}
+				message, err := messageArgument(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				if !equalVariables(args[0], args[1]) {
+					return Variable{}, assertionError(message, "expected "+formatValue(args[1])+", got "+formatValue(args[0]))
+				}
+
+				return noneVariable(), nil
+			},
+			"assert_ne": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 2, 3); err != nil {
+					return Variable{}, err
+				}
+				message, err := messageArgument(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				if equalVariables(args[0], args[1]) {
+					return Variable{}, assertionError(message, "expected a value other than "+formatValue(args[1]))
+				}
+
+				return noneVariable(), nil
+			},
+			// test.assert_throws("function_name", "kind") calls the function without
+			// arguments, use test.catch to look at the error it threw
+			"assert_throws": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 2); err != nil {
+					return Variable{}, err
+				}
+				name, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+				kind := ""
+				if len(args) == 2 {
+					kind, err = stringArgument(call, args, 1)
+					if err != nil {
+						return Variable{}, err
+					}
+				}
+				if call.Interpreter.Functions[name].Name == "" {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, "test.assert_throws expects the name of a gal function, "+name+" not found")
+				}
+
+				thrown := protect(call.Interpreter, func() {
+					call.Call(name)
+				})
+				if thrown == nil {
+					return Variable{}, NewRuntimeError(ErrorKindAssertion, "expected "+name+" to throw")
+				}
+
+				if kind != "" && thrown.Kind != kind {
+					return Variable{}, NewRuntimeError(ErrorKindAssertion, "expected "+name+" to throw an error of kind "+kind+", got "+thrown.Kind+": "+thrown.Message)
+				}
+
+				return noneVariable(), nil
+			},
+			// test.catch("function_name") calls the function without arguments and
+			// returns the error it threw, nuthin if it did not throw
+			"catch": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				name, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+				if call.Interpreter.Functions[name].Name == "" {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, "test.catch expects the name of a gal function, "+name+" not found")
+				}
+
+				thrown := protect(call.Interpreter, func() {
+					call.Call(name)
+				})
+				if thrown == nil {
+					return noneVariable(), nil
+				}
+
+				return errorVariable(thrown), nil
+			},
+			"fail": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 0, 1); err != nil {
+					return Variable{}, err
+				}
+				message, err := messageArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				return Variable{}, assertionError(message, "failed")
+			},
+		},
+	}
+}
+
+// the optional message of an assertion is its last argument, fire test.fail()
+// passes nuthin for it
+func messageArgument(call *CallContext, args []Variable, i int) (string, error) {
+	if i >= len(args) || args[i].Type == genalphatypes.ASTNodeTypeNone {
+		return "", nil
+	}
+
+	return stringArgument(call, args, i)
+}
+
+// the message passed to an assertion is put in front of the default one
+func assertionError(message string, defaultMessage string) *RuntimeError {
+	if message == "" {
+		return NewRuntimeError(ErrorKindAssertion, defaultMessage)
+	}
+
+	return NewRuntimeError(ErrorKindAssertion, message+": "+defaultMessage)
+}
+
+// values are equal when they have the same type and value and their indecies
+// are equal, numbers are compared by value so 1 and 1.0 are equal
+func equalVariables(a Variable, b Variable) bool {
+	if a.Type != b.Type {
+		return false
+	}
+
+	if a.Type == genalphatypes.ASTNodeTypeNumber {
+		left, leftErr := strconv.ParseFloat(a.Value, 64)
+		right, rightErr := strconv.ParseFloat(b.Value, 64)
+		if leftErr != nil || rightErr != nil || left != right {
+			return false
+		}
+	} else if a.Value != b.Value {
+		return false
+	}
+
+	if len(a.Indecies) != len(b.Indecies) {
+		return false
+	}
+
+	for key, value := range a.Indecies {
+		other := b.Indecies[key]
+		if (value == nil) != (other == nil) {
+			return false
+		}
+		if value != nil && !equalVariables(*value, *other) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func formatValue(variable Variable) string {
+	switch variable.Type {
+	case genalphatypes.ASTNodeTypeString:
+		return strconv.Quote(variable.Value)
+	case genalphatypes.ASTNodeTypeNone:
+		return string(genalphatypes.KeywordNone)
+	default:
+		return variable.Value
+	}
+}
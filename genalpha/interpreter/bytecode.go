@@ -0,0 +1,507 @@
+package interpreter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// the bytecode is a flat list of instructions for a stack machine, every gal
+// function is compiled separately and its variables are resolved to local slots
+type opcode uint8
+
+const (
+	opConstant      opcode = iota // push constants[a]
+	opNone                        // push nuthin
+	opLoad                        // push local slot a, falls back to the global names[a]
+	opDeclare                     // pop into local slot a
+	opDeclareGlobal               // pop into the global strings[a]
+	opAssign                      // pop into the already declared local slot a or the global names[a]
+	opMemberLoad                  // pop index, push [names[a] index]
+	opMemberStore                 // pop value, pop index, [names[a] index] = value
+	opAdd
+	opSubtract
+	opMultiply
+	opPower
+	opDivide
+	opModulo
+	opEqual
+	opStrictEqual
+	opNotEqual
+	opStrictNotEqual
+	opGreater
+	opLess
+	opGreaterEqual
+	opLessEqual
+	opAnd
+	opOr
+	opNot
+	opJump          // jump to a
+	opJumpUnless    // pop condition, jump to a when it is nay, b is 1 for while loops
+	opCall          // call functions[a] with b arguments from the stack
+	opCallNative    // call natives[a] with b arguments from the stack
+	opFindName      // push the function strings[a] declared while running, fails like the ast walker if it is missing or takes more than b arguments
+	opSkipArgument  // push nuthin and jump to a if the function found b values down the stack takes b arguments or fewer
+	opCallName      // pop b arguments and the function found below them and call it
+	opReturnIfValue // pop, return from the function if the value is not nuthin
+	opTry           // run tries[a], the code of the block follows this instruction
+	opThrow         // pop, throw the value as an error
+	opFail          // panic with strings[a], as a RuntimeError of the kind strings[b] if it is not empty
+	opLine          // the statement on line a, column b starts, only emitted when profiling, measuring coverage or tracing
+	opBranch        // record the condition on top of the stack for the coverage of the statement on line a, column b
+	opInterpret     // run nodes[a] with the ast walker, for gyat and lowkey inside functions
+)
+
+type instruction struct {
+	op opcode
+	a  int
+	b  int
+}
+
+type compiledFunction struct {
+	function  Function
+	code      []instruction
+	lines     []int // source line of every instruction
+	constants []vmValue
+	names     []string // local slot names
+	strings   []string
+	argSlots  []int
+	tries     []tryRange
+	nodes     []genalphatypes.ASTNode
+	maxStack  int
+}
+
+// a tryna block is compiled to its body, sus and anyways code right after the
+// opTry, each part ends where the next one starts
+type tryRange struct {
+	bodyEnd   int
+	hasCatch  bool
+	catchSlot int // -1 if the error is not bound to a variable
+	catchEnd  int
+	end       int
+}
+
+type compiledNative struct {
+	name     string
+	module   *NativeModule
+	function NativeFunction
+}
+
+type compiler struct {
+	vm       *vm
+	function *compiledFunction
+	slots    map[string]int
+	depth    int // stack depth after the last emitted instruction
+	line     int // line of the statement being compiled
+}
+
+var binaryOpcodes = map[string]opcode{
+	"+":   opAdd,
+	"-":   opSubtract,
+	"*":   opMultiply,
+	"**":  opPower,
+	"/":   opDivide,
+	"%":   opModulo,
+	"==":  opEqual,
+	"===": opStrictEqual,
+	"!=":  opNotEqual,
+	"!==": opStrictNotEqual,
+	">":   opGreater,
+	"<":   opLess,
+	">=":  opGreaterEqual,
+	"<=":  opLessEqual,
+	"&&":  opAnd,
+	"||":  opOr,
+}
+
+func compileFunction(machine *vm, function Function) *compiledFunction {
+	c := compiler{
+		vm: machine,
+		function: &compiledFunction{
+			function: function,
+		},
+		slots: map[string]int{},
+	}
+
+	for _, arg := range function.Args {
+		c.function.argSlots = append(c.function.argSlots, c.slot(arg.Value))
+	}
+
+	for _, node := range function.Body {
+		c.statement(node)
+	}
+
+	return c.function
+}
+
+func (c *compiler) emit(op opcode, a int, b int) int {
+	switch op {
+	case opConstant, opNone, opLoad, opFindName:
+		c.depth++
+	case opDeclare, opDeclareGlobal, opAssign, opJumpUnless, opReturnIfValue, opThrow:
+		c.depth--
+	case opMemberStore:
+		c.depth -= 2
+	case opCall, opCallNative:
+		c.depth += 1 - b
+	case opCallName:
+		c.depth -= b
+	case opMemberLoad, opNot, opJump, opTry, opFail, opLine, opBranch, opInterpret, opSkipArgument:
+	default: // binary operations
+		c.depth--
+	}
+
+	if c.depth > c.function.maxStack {
+		c.function.maxStack = c.depth
+	}
+
+	c.function.code = append(c.function.code, instruction{
+		op: op,
+		a:  a,
+		b:  b,
+	})
+	c.function.lines = append(c.function.lines, c.line)
+
+	return len(c.function.code) - 1
+}
+
+// points the jump at index to the next emitted instruction
+func (c *compiler) patch(index int) {
+	c.function.code[index].a = len(c.function.code)
+}
+
+func (c *compiler) slot(name string) int {
+	if slot, ok := c.slots[name]; ok {
+		return slot
+	}
+
+	c.function.names = append(c.function.names, name)
+	c.slots[name] = len(c.function.names) - 1
+	return len(c.function.names) - 1
+}
+
+func (c *compiler) constant(value vmValue) int {
+	c.function.constants = append(c.function.constants, value)
+	return len(c.function.constants) - 1
+}
+
+func (c *compiler) string(value string) int {
+	c.function.strings = append(c.function.strings, value)
+	return len(c.function.strings) - 1
+}
+
+// kind is empty for failures the ast walker does not raise as a RuntimeError
+func (c *compiler) fail(kind string, message string) {
+	c.emit(opFail, c.string(message), c.string(kind))
+}
+
+func (c *compiler) branch(node genalphatypes.ASTNode) {
+	if c.vm.interpreterState.Coverage != nil {
+		c.emit(opBranch, node.Line, node.Column)
+	}
+}
+
+func (c *compiler) statement(node genalphatypes.ASTNode) {
+	if node.Line != 0 {
+		c.line = node.Line
+		if c.vm.interpreterState.Profiler != nil || c.vm.interpreterState.Coverage != nil || c.vm.interpreterState.Tracer != nil {
+			c.emit(opLine, node.Line, node.Column)
+		}
+	}
+
+	switch node.Type {
+	case genalphatypes.ASTNodeTypeMemberAssignment:
+		c.expression(node.Children[1])
+		c.expression(node.Children[2])
+		c.emit(opMemberStore, c.slot(node.Children[0].Value), 0)
+	case genalphatypes.ASTNodeTypeVariableDeclaration:
+		name := node.Children[0].Value
+		c.expression(node.Children[1])
+		if strings.HasPrefix(name, "GLOBAL_") {
+			c.emit(opDeclareGlobal, c.string(name), 0)
+			return
+		}
+
+		c.emit(opDeclare, c.slot(name), 0)
+	case genalphatypes.ASTNodeTypeVariableAssignment:
+		c.expression(node.Children[1])
+		c.emit(opAssign, c.slot(node.Children[0].Value), 0)
+	case genalphatypes.ASTNodeTypeIf:
+		c.expression(node.Children[0])
+		c.branch(node)
+		jump := c.emit(opJumpUnless, 0, 0)
+		for _, child := range node.Children[1:] {
+			c.statement(child)
+		}
+		c.patch(jump)
+	case genalphatypes.ASTNodeTypeWhile:
+		start := len(c.function.code)
+		c.expression(node.Children[0])
+		c.branch(node)
+		jump := c.emit(opJumpUnless, 0, 1)
+		for _, child := range node.Children[1:] {
+			c.statement(child)
+		}
+		c.emit(opJump, start, 0)
+		c.patch(jump)
+	case genalphatypes.ASTNodeTypeReturn:
+		if len(node.Children) != 1 {
+			c.fail(ErrorKindRuntime, "return should be done with one argument, the value to return, such as 'rizzult \"returned value\"'")
+			return
+		}
+
+		c.expression(node.Children[0])
+		c.emit(opReturnIfValue, 0, 0)
+	case genalphatypes.ASTNodeTypeFunctionCall:
+		c.call(node)
+		c.emit(opReturnIfValue, 0, 0)
+	case genalphatypes.ASTNodeTypeTry:
+		c.try(node)
+	case genalphatypes.ASTNodeTypeThrow:
+		if len(node.Children) != 1 {
+			c.fail(ErrorKindRuntime, "yeet should be done with one argument, the error to throw, such as 'yeet \"something went wrong\"'")
+			return
+		}
+
+		c.expression(node.Children[0])
+		c.emit(opThrow, 0, 0)
+	case genalphatypes.ASTNodeTypeImport, genalphatypes.ASTNodeTypeFunctionDeclaration:
+		// they declare functions while running, the vm compiles them afterwards
+		c.function.nodes = append(c.function.nodes, node)
+		c.emit(opInterpret, len(c.function.nodes)-1, 0)
+	case genalphatypes.ASTNodeTypeFunctionArgument:
+	default:
+		c.fail("", "Invalid AST node type"+fmt.Sprint(node.Type))
+	}
+}
+
+func (c *compiler) expression(node genalphatypes.ASTNode) {
+	switch node.Type {
+	case genalphatypes.ASTNodeTypeIdentifier:
+		c.emit(opLoad, c.slot(node.Value), 0)
+	case genalphatypes.ASTNodeTypeNumber, genalphatypes.ASTNodeTypeString, genalphatypes.ASTNodeTypeBoolean:
+		value := fromVariable(Variable{
+			Type:  node.Type,
+			Value: node.Value,
+		})
+
+		// numbers written the way they would be formatted can be compared without formatting
+		if value.hasNum && formatNumber(value.num) == value.str {
+			value.hasStr = false
+		}
+
+		c.emit(opConstant, c.constant(value), 0)
+	case genalphatypes.ASTNodeTypeBlock:
+		if len(node.Children) == 0 {
+			c.fail("", "Invalid empty block")
+			return
+		}
+
+		c.expression(node.Children[0])
+	case genalphatypes.ASTNodeTypeBinaryOperation:
+		c.expression(node.Children[0])
+		c.expression(node.Children[1])
+
+		op, ok := binaryOpcodes[node.Value]
+		if !ok {
+			c.fail(ErrorKindRuntime, "Invalid binary operation "+node.Value)
+			return
+		}
+
+		c.emit(op, 0, 0)
+	case genalphatypes.ASTNodeTypeUnaryOperation:
+		c.expression(node.Children[0])
+		if node.Value != "!" {
+			c.fail(ErrorKindRuntime, "Invalid unary operation "+node.Value)
+			return
+		}
+
+		c.emit(opNot, 0, 0)
+	case genalphatypes.ASTNodeTypeFunctionCall:
+		c.call(node)
+	case genalphatypes.ASTNodeTypeMemberAccess:
+		c.expression(node.Children[1])
+		c.emit(opMemberLoad, c.slot(node.Children[0].Value), 0)
+	case genalphatypes.ASTNodeTypeExpression:
+		if len(node.Children) == 0 {
+			c.emit(opNone, 0, 0)
+			return
+		}
+
+		c.expression(node.Children[0])
+	case genalphatypes.ASTNodeTypeNone:
+		c.emit(opNone, 0, 0)
+	default:
+		c.fail("", "Invalid expression node type "+fmt.Sprint(node.Type))
+	}
+}
+
+func (c *compiler) try(node genalphatypes.ASTNode) {
+	block := splitTry(node)
+	index := len(c.function.tries)
+	c.function.tries = append(c.function.tries, tryRange{})
+	c.emit(opTry, index, 0)
+
+	compiled := tryRange{
+		catchSlot: -1,
+	}
+
+	for _, child := range block.body {
+		c.statement(child)
+	}
+	compiled.bodyEnd = len(c.function.code)
+
+	if block.catch != nil {
+		compiled.hasCatch = true
+		if block.catch.Value != "" {
+			compiled.catchSlot = c.slot(block.catch.Value)
+		}
+
+		for _, child := range block.catchBody {
+			c.statement(child)
+		}
+	}
+	compiled.catchEnd = len(c.function.code)
+
+	for _, child := range block.finallyBody {
+		c.statement(child)
+	}
+	compiled.end = len(c.function.code)
+
+	c.function.tries[index] = compiled
+}
+
+func (c *compiler) call(node genalphatypes.ASTNode) {
+	name := node.Children[0].Value
+
+	if index, ok := c.vm.functionIndex[name]; ok {
+		function := c.vm.interpreterState.Functions[name]
+		if len(function.Args) > len(node.Children)-1 {
+			c.fail(ErrorKindArgument, "Invalid number of arguments for function "+name)
+			return
+		}
+
+		// like the ast walker only the declared arguments are evaluated
+		for i := range function.Args {
+			c.expression(node.Children[i+1])
+		}
+
+		c.emit(opCall, index, len(function.Args))
+		return
+	}
+
+	module, nativeFunction := lookupNative(c.vm.interpreterState, name)
+	if nativeFunction == nil {
+		// the function can still be declared by gyat or lowkey before the call
+		// runs, like the ast walker it is looked up first and only the
+		// arguments it declares are evaluated
+		c.emit(opFindName, c.string(name), len(node.Children)-1)
+		for i, argNode := range node.Children[1:] {
+			skip := c.emit(opSkipArgument, 0, i)
+			c.expression(argNode)
+			c.patch(skip)
+		}
+
+		c.emit(opCallName, 0, len(node.Children)-1)
+		return
+	}
+
+	for _, argNode := range node.Children[1:] {
+		c.expression(argNode)
+	}
+
+	c.vm.natives = append(c.vm.natives, compiledNative{
+		name:     name,
+		module:   module,
+		function: nativeFunction,
+	})
+	c.emit(opCallNative, len(c.vm.natives)-1, len(node.Children)-1)
+}
+
+// vmValue is the vm representation of a Variable, numbers are kept as float64
+// so they are only parsed once
+type vmValue struct {
+	kind     genalphatypes.ASTNodeType
+	str      string
+	num      float64
+	hasStr   bool // str holds the Value the ast walker would have, always true for non numbers
+	hasNum   bool
+	indecies map[string]*Variable
+}
+
+// marks local slots that have not been declared yet
+const kindUndefined genalphatypes.ASTNodeType = -1
+
+var noneValue = vmValue{
+	kind:   genalphatypes.ASTNodeTypeNone,
+	hasStr: true,
+}
+
+func fromVariable(variable Variable) vmValue {
+	value := vmValue{
+		kind:     variable.Type,
+		str:      variable.Value,
+		hasStr:   true,
+		indecies: variable.Indecies,
+	}
+
+	if variable.Type == genalphatypes.ASTNodeTypeNumber {
+		number, err := strconv.ParseFloat(variable.Value, 64)
+		if err == nil {
+			value.num = number
+			value.hasNum = true
+		}
+	}
+
+	return value
+}
+
+func numberValue(number float64) vmValue {
+	return vmValue{
+		kind:   genalphatypes.ASTNodeTypeNumber,
+		num:    number,
+		hasNum: true,
+	}
+}
+
+func booleanValue(value bool) vmValue {
+	if value {
+		return vmValue{
+			kind:   genalphatypes.ASTNodeTypeBoolean,
+			str:    string(genalphatypes.KeywordTrue),
+			hasStr: true,
+		}
+	}
+
+	return vmValue{
+		kind:   genalphatypes.ASTNodeTypeBoolean,
+		str:    string(genalphatypes.KeywordFalse),
+		hasStr: true,
+	}
+}
+
+func (value vmValue) text() string {
+	if value.hasStr {
+		return value.str
+	}
+
+	return formatNumber(value.num)
+}
+
+func (value vmValue) number() float64 {
+	if value.hasNum {
+		return value.num
+	}
+
+	return parseNumber(value.text())
+}
+
+func (value vmValue) variable() Variable {
+	return Variable{
+		Type:     value.kind,
+		Value:    value.text(),
+		Indecies: value.indecies,
+	}
+}
@@ -0,0 +1,333 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+	"bobik.squidwock.com/root/gal/genalpha/interpreter"
+	"bobik.squidwock.com/root/gal/genalpha/lexer"
+	"bobik.squidwock.com/root/gal/genalpha/parser"
+)
+
+// programs whose output differs from run to run, so the engines can not be compared
+var nondeterministic = map[string]string{
+	"time.monotonic": "prints the current time and how long it slept",
+}
+
+var galBlock = regexp.MustCompile("(?s)```gal\n(.*?)```")
+
+// the examples and every program in the documentation that has a main function
+func examplePrograms(t *testing.T) map[string]string {
+	programs := map[string]string{}
+
+	examples, err := filepath.Glob("../../examples/*.gal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, example := range examples {
+		contents, err := os.ReadFile(example)
+		if err != nil {
+			t.Fatal(err)
+		}
+		programs[filepath.Base(example)] = string(contents)
+	}
+
+	documents := []string{"../../README.md"}
+	err = filepath.WalkDir("../../docs", func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && strings.HasSuffix(path, ".md") {
+			documents = append(documents, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, document := range documents {
+		contents, err := os.ReadFile(document)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, match := range galBlock.FindAllStringSubmatchIndex(string(contents), -1) {
+			source := string(contents[match[2]:match[3]])
+			if !strings.Contains(source, "lowkey main") {
+				continue
+			}
+
+			line := bytes.Count(contents[:match[0]], []byte("\n")) + 1
+			programs[fmt.Sprintf("%s:%d", strings.TrimPrefix(document, "../../"), line)] = source
+		}
+	}
+
+	return programs
+}
+
+type result struct {
+	stdout  string
+	code    int
+	failure string // the uncaught error, if any
+}
+
+// runs the program in an empty directory with the same arguments and seed for both engines
+func run(t *testing.T, ast genalphatypes.ASTNode, setup func(dir string), bytecode bool) (r result) {
+	dir := t.TempDir()
+	if setup != nil {
+		setup(dir)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	var stdout bytes.Buffer
+	interpreterState := interpreter.NewInterpreter([]string{"one", "two"})
+	for _, module := range interpreter.DefaultModules() {
+		interpreterState.RegisterModule(module)
+	}
+	interpreterState.Seed(1)
+	interpreterState.Stdout = interpreter.NewWriterOutput(&stdout)
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			r.failure = fmt.Sprint(recovered)
+			r.code = 1
+		}
+		interpreterState.Stdout.Flush()
+		r.stdout = stdout.String()
+	}()
+
+	interpreterState.Load(&ast, "./main.gal")
+	if bytecode {
+		r.code = interpreterState.RunBytecode()
+	} else {
+		r.code = interpreterState.Run()
+	}
+
+	return r
+}
+
+func compare(t *testing.T, ast genalphatypes.ASTNode, setup func(dir string)) result {
+	walker := run(t, ast, setup, false)
+	vm := run(t, ast, setup, true)
+
+	if walker != vm {
+		t.Errorf("the engines differ\nast walker: %+v\nbytecode:   %+v", walker, vm)
+	}
+
+	return walker
+}
+
+func TestBytecodeMatchesInterpreter(t *testing.T) {
+	for name, source := range examplePrograms(t) {
+		t.Run(name, func(t *testing.T) {
+			for call, reason := range nondeterministic {
+				if strings.Contains(source, call) {
+					t.Skip(reason)
+				}
+			}
+
+			compare(t, parser.Parse(lexer.Lex(source)), nil)
+		})
+	}
+}
+
+// the parser only allows gyat and lowkey at the top level, but the vm runs
+// them inside functions too, like the ast walker does. Arguments the function
+// does not declare are not evaluated
+func TestBytecodeDeclaresFunctionsWhileRunning(t *testing.T) {
+	ast := parser.Parse(lexer.Lex(`lowkey main{}
+    fire helped("yes", fire std.println("extra"))
+    fax r = fire inner(41)
+    fire std.println(r)
+end
+
+lowkey inner{a}
+    rizzult a + 1
+end
+`))
+
+	main, inner := ast.Children[0], ast.Children[1]
+	imported := genalphatypes.ASTNode{
+		Type: genalphatypes.ASTNodeTypeImport,
+		Children: []genalphatypes.ASTNode{
+			{Type: genalphatypes.ASTNodeTypeString, Value: "helper.gal"},
+		},
+	}
+	main.Children = append([]genalphatypes.ASTNode{main.Children[0], imported, inner}, main.Children[1:]...)
+	ast.Children = []genalphatypes.ASTNode{main}
+
+	r := compare(t, ast, func(dir string) {
+		helper := "lowkey helped{x}\n    fire std.println(\"helped \" + x)\nend\n"
+		if err := os.WriteFile(filepath.Join(dir, "helper.gal"), []byte(helper), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if r.stdout != "helped yes\n42\n" || r.failure != "" {
+		t.Errorf("got %+v", r)
+	}
+}
+
+// computed numbers, numbers from native functions and literals are formatted
+// the same way, so == compares them by value
+func TestNumbersAreFormattedTheSame(t *testing.T) {
+	r := compare(t, parser.Parse(lexer.Lex(`lowkey main{}
+    fire std.println(10000000 * 2)
+    fire std.println(fire math.floor(20000000.5) == 10000000 * 2)
+    fire std.println(20000000 == 10000000 * 2)
+    fire std.println(1 / 100000)
+    fire std.println(30000000 % 7 == 30000000 - 7 * 4285714)
+    fire std.println(1000000 * 1000000 * 1000000 * 1000000)
+end
+`)), nil)
+
+	if want := "20000000\nyay\nyay\n0.00001\nyay\n1e+24\n"; r.stdout != want || r.failure != "" {
+		t.Errorf("got %+v, want %q", r, want)
+	}
+}
+
+func benchmark(b *testing.B, filename string, bytecode bool) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ast := parser.Parse(lexer.Lex(string(contents)))
+
+	for i := 0; i < b.N; i++ {
+		interpreterState := interpreter.NewInterpreter([]string{})
+		for _, module := range interpreter.DefaultModules() {
+			interpreterState.RegisterModule(module)
+		}
+		interpreterState.Stdout = interpreter.NewWriterOutput(io.Discard)
+
+		interpreterState.Load(&ast, "./main.gal")
+		if bytecode {
+			interpreterState.RunBytecode()
+		} else {
+			interpreterState.Run()
+		}
+	}
+}
+
+func BenchmarkCallsInterpreter(b *testing.B) { benchmark(b, "testdata/bench_calls.gal", false) }
+func BenchmarkCallsBytecode(b *testing.B)    { benchmark(b, "testdata/bench_calls.gal", true) }
+func BenchmarkLoopInterpreter(b *testing.B)  { benchmark(b, "testdata/bench_loop.gal", false) }
+func BenchmarkLoopBytecode(b *testing.B)     { benchmark(b, "testdata/bench_loop.gal", true) }
+
+// arguments are evaluated left to right before the call, and an error thrown
+// while evaluating an argument is caught or reported the same way. The parser
+// nests calls one level deep, so the programs stay within that
+func TestBytecodeEvaluatesArgumentsTheSame(t *testing.T) {
+	for name, test := range map[string]struct {
+		source string
+		want   result
+	}{
+		"calls in arguments with side effects": {
+			source: `lowkey main{}
+    fax GLOBAL_count = 0
+    fire std.println(fire bump("a"), fire bump("b"), fire bump("c"))
+    fire show(fire bump("d"), fire bump("e"))
+    fax r = fire bump("f")
+    fax r = fire add(r, GLOBAL_count)
+    fire std.println(r)
+end
+
+lowkey bump{name}
+    fire std.print(name + " ")
+    GLOBAL_count = GLOBAL_count + 1
+    rizzult GLOBAL_count
+end
+
+lowkey show{x y}
+    fire std.println(x, y, GLOBAL_count)
+end
+
+lowkey add{a b}
+    rizzult a + b
+end
+`,
+			want: result{stdout: "a b c 1\n2\n3\nd e 4\n5\n5\nf 12\n"},
+		},
+		"errors in arguments are caught": {
+			source: `lowkey main{}
+    tryna
+        fire std.println("before", fire fail("inner"), fire noisy())
+    sus err
+        fire std.println("caught", err)
+    end
+    tryna
+        fire std.println(fire math.sqrt("text"))
+    sus err
+        fire std.println("caught native", err)
+    end
+    tryna
+        fire missing(fire noisy())
+    sus err
+        fire std.println("caught missing", err)
+    end
+end
+
+lowkey fail{message}
+    yeet fire std.error(message)
+end
+
+lowkey noisy{}
+    fire std.println("noisy ran")
+end
+`,
+			want: result{stdout: "caught\ninner\ncaught native\nmath.sqrt expects a number as argument 1, got \"text\"\ncaught missing\nFunction missing not found\n"},
+		},
+		"uncaught error in a native argument": {
+			source: `lowkey main{}
+    fire std.println("start")
+    fire show(fire math.sqrt("text"))
+    fire std.println("not reached")
+end
+
+lowkey show{x}
+    fire std.println(x)
+end
+`,
+		},
+		"uncaught error in a function argument": {
+			source: `lowkey main{}
+    fire std.println("start")
+    fire show(fire fail("deep"))
+    fire std.println("not reached")
+end
+
+lowkey show{x}
+    fire std.println(x)
+end
+
+lowkey fail{message}
+    yeet fire std.error(message)
+end
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := compare(t, parser.Parse(lexer.Lex(test.source)), nil)
+			if test.want != (result{}) && r != test.want {
+				t.Errorf("got %+v, want %+v", r, test.want)
+			}
+			if test.want == (result{}) && (r.stdout != "start\n" || r.code != 1 || r.failure == "") {
+				t.Errorf("expected the program to stop after start, got %+v", r)
+			}
+		})
+	}
+}
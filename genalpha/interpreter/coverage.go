@@ -0,0 +1,351 @@
+package interpreter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// Coverage records how often every statement of a gal program ran, which way
+// the conditions of foreal and durin went and how often functions were called.
+// Set it on the interpreter before the program is loaded, files are added as
+// they are loaded so imported files are covered too. One Coverage can be set on
+// several interpreters, gal test runs every test in its own.
+type Coverage struct {
+	files   map[string]*fileCoverage
+	ignored map[string]bool
+}
+
+// statements are identified by the line and column of their first token
+type coveragePosition struct {
+	line   int
+	column int
+}
+
+type fileCoverage struct {
+	statements map[coveragePosition]int
+	branches   map[coveragePosition]*[2]int // times the condition was yay and nay
+	functions  map[string]*coveredFunction
+}
+
+type coveredFunction struct {
+	line  int
+	calls int
+}
+
+func NewCoverage() *Coverage {
+	return &Coverage{
+		files:   map[string]*fileCoverage{},
+		ignored: map[string]bool{},
+	}
+}
+
+// Ignore leaves the file out of the coverage, it is still run
+func (coverage *Coverage) Ignore(filename string) {
+	coverage.ignored[filename] = true
+}
+
+// adds the statements of a loaded file, a file already added is kept as it is
+func (coverage *Coverage) add(filename string, ast genalphatypes.ASTNode) {
+	if coverage.files[filename] != nil || coverage.ignored[filename] {
+		return
+	}
+
+	file := &fileCoverage{
+		statements: map[coveragePosition]int{},
+		branches:   map[coveragePosition]*[2]int{},
+		functions:  map[string]*coveredFunction{},
+	}
+	file.addStatements(ast.Children)
+	coverage.files[filename] = file
+}
+
+func (file *fileCoverage) addStatements(nodes []genalphatypes.ASTNode) {
+	for _, node := range nodes {
+		switch node.Type {
+		case genalphatypes.ASTNodeTypeFunctionDeclaration:
+			function := NewFunction(node, "")
+			file.functions[function.Name] = &coveredFunction{line: node.Line}
+			file.addStatements(function.Body)
+			continue
+		case genalphatypes.ASTNodeTypeCatch, genalphatypes.ASTNodeTypeFinally:
+			// only mark where the sus and anyways blocks start
+			continue
+		}
+
+		if node.Line == 0 {
+			continue
+		}
+
+		position := coveragePosition{line: node.Line, column: node.Column}
+		file.statements[position] = 0
+		switch node.Type {
+		case genalphatypes.ASTNodeTypeIf, genalphatypes.ASTNodeTypeWhile:
+			file.branches[position] = &[2]int{}
+			file.addStatements(node.Children[1:])
+		case genalphatypes.ASTNodeTypeTry:
+			file.addStatements(node.Children)
+		}
+	}
+}
+
+func (coverage *Coverage) statement(filename string, line int, column int) {
+	file := coverage.files[filename]
+	if file == nil {
+		return
+	}
+
+	position := coveragePosition{line: line, column: column}
+	if count, ok := file.statements[position]; ok {
+		file.statements[position] = count + 1
+	}
+}
+
+func (coverage *Coverage) branch(filename string, line int, column int, condition bool) {
+	file := coverage.files[filename]
+	if file == nil {
+		return
+	}
+
+	branches := file.branches[coveragePosition{line: line, column: column}]
+	if branches == nil {
+		return
+	}
+
+	if condition {
+		branches[0]++
+	} else {
+		branches[1]++
+	}
+}
+
+func (coverage *Coverage) call(function Function) {
+	file := coverage.files[function.Filename]
+	if file == nil || file.functions[function.Name] == nil {
+		return
+	}
+
+	file.functions[function.Name].calls++
+}
+
+func coverStatement(interpreterState *InterpreterState, node genalphatypes.ASTNode, filename string) {
+	if node.Line != 0 {
+		interpreterState.Coverage.statement(currentFilename(interpreterState, filename), node.Line, node.Column)
+	}
+}
+
+func coverBranch(interpreterState *InterpreterState, node genalphatypes.ASTNode, condition Variable) {
+	if interpreterState.Coverage != nil && condition.Type == genalphatypes.ASTNodeTypeBoolean {
+		interpreterState.Coverage.branch(currentFilename(interpreterState, ""), node.Line, node.Column, condition.Value == string(genalphatypes.KeywordTrue))
+	}
+}
+
+// CoverageCounts are the covered and total number of statements, branches and
+// functions of a file or a whole program, a branch is one way a condition can go
+type CoverageCounts struct {
+	Statements, StatementsCovered int
+	Branches, BranchesCovered     int
+	Functions, FunctionsCovered   int
+}
+
+func (counts *CoverageCounts) add(other CoverageCounts) {
+	counts.Statements += other.Statements
+	counts.StatementsCovered += other.StatementsCovered
+	counts.Branches += other.Branches
+	counts.BranchesCovered += other.BranchesCovered
+	counts.Functions += other.Functions
+	counts.FunctionsCovered += other.FunctionsCovered
+}
+
+func (file *fileCoverage) counts() CoverageCounts {
+	counts := CoverageCounts{}
+	for _, count := range file.statements {
+		counts.Statements++
+		if count != 0 {
+			counts.StatementsCovered++
+		}
+	}
+	for _, branches := range file.branches {
+		for _, count := range branches {
+			counts.Branches++
+			if count != 0 {
+				counts.BranchesCovered++
+			}
+		}
+	}
+	for _, function := range file.functions {
+		counts.Functions++
+		if function.calls != 0 {
+			counts.FunctionsCovered++
+		}
+	}
+
+	return counts
+}
+
+// Files returns the covered files sorted by name
+func (coverage *Coverage) Files() []string {
+	files := []string{}
+	for filename := range coverage.files {
+		files = append(files, filename)
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+// Counts returns the counts of the file, or of all files if filename is empty
+func (coverage *Coverage) Counts(filename string) CoverageCounts {
+	if filename != "" {
+		file := coverage.files[filename]
+		if file == nil {
+			return CoverageCounts{}
+		}
+		return file.counts()
+	}
+
+	counts := CoverageCounts{}
+	for _, file := range coverage.files {
+		counts.add(file.counts())
+	}
+
+	return counts
+}
+
+func coveragePercent(covered int, total int) string {
+	if total == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.1f%%", 100*float64(covered)/float64(total))
+}
+
+// WriteSummary writes the statement, branch and function coverage of every
+// file and of all of them together
+func (coverage *Coverage) WriteSummary(out io.Writer) {
+	files := coverage.Files()
+	width := len("coverage")
+	for _, filename := range files {
+		width = max(width, len(filename))
+	}
+
+	line := func(name string, counts CoverageCounts) {
+		fmt.Fprintf(out, "%-*s  %6s %-9s  %6s %-9s  %6s %s\n", width, name,
+			coveragePercent(counts.StatementsCovered, counts.Statements), fmt.Sprintf("(%d/%d)", counts.StatementsCovered, counts.Statements),
+			coveragePercent(counts.BranchesCovered, counts.Branches), fmt.Sprintf("(%d/%d)", counts.BranchesCovered, counts.Branches),
+			coveragePercent(counts.FunctionsCovered, counts.Functions), fmt.Sprintf("(%d/%d)", counts.FunctionsCovered, counts.Functions))
+	}
+
+	fmt.Fprintf(out, "%-*s  %-16s  %-16s  %s\n", width, "coverage", "statements", "branches", "functions")
+	for _, filename := range files {
+		line(filename, coverage.files[filename].counts())
+	}
+	line("total", coverage.Counts(""))
+}
+
+// the coverage of a line, the count of a line is the lowest count of the
+// statements on it so a line only counts as run when all of them ran
+type lineCoverage struct {
+	statements int
+	count      int
+	branches   [][2]int
+}
+
+func (file *fileCoverage) lines() map[int]*lineCoverage {
+	lines := map[int]*lineCoverage{}
+	positions := []coveragePosition{}
+	for position := range file.statements {
+		positions = append(positions, position)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].line != positions[j].line {
+			return positions[i].line < positions[j].line
+		}
+		return positions[i].column < positions[j].column
+	})
+
+	for _, position := range positions {
+		count := file.statements[position]
+		line := lines[position.line]
+		if line == nil {
+			line = &lineCoverage{count: count}
+			lines[position.line] = line
+		}
+
+		line.statements++
+		line.count = min(line.count, count)
+		if branches := file.branches[position]; branches != nil {
+			line.branches = append(line.branches, *branches)
+		}
+	}
+
+	return lines
+}
+
+func sortedLines(lines map[int]*lineCoverage) []int {
+	numbers := []int{}
+	for number := range lines {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	return numbers
+}
+
+// WriteLCOV writes the coverage in the lcov tracefile format that coverage
+// services and editor plugins read
+func (coverage *Coverage) WriteLCOV(out io.Writer) error {
+	var lcov strings.Builder
+	lcov.WriteString("TN:\n")
+
+	for _, filename := range coverage.Files() {
+		file := coverage.files[filename]
+		counts := file.counts()
+		fmt.Fprintf(&lcov, "SF:%s\n", filename)
+
+		names := []string{}
+		for name := range file.functions {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return file.functions[names[i]].line < file.functions[names[j]].line
+		})
+		for _, name := range names {
+			fmt.Fprintf(&lcov, "FN:%d,%s\n", file.functions[name].line, name)
+		}
+		for _, name := range names {
+			fmt.Fprintf(&lcov, "FNDA:%d,%s\n", file.functions[name].calls, name)
+		}
+		fmt.Fprintf(&lcov, "FNF:%d\nFNH:%d\n", counts.Functions, counts.FunctionsCovered)
+
+		lines := file.lines()
+		for _, number := range sortedLines(lines) {
+			for block, branches := range lines[number].branches {
+				for branch, count := range branches {
+					// a condition that never ran has not taken any branch
+					taken := fmt.Sprint(count)
+					if branches[0]+branches[1] == 0 {
+						taken = "-"
+					}
+					fmt.Fprintf(&lcov, "BRDA:%d,%d,%d,%s\n", number, block, branch, taken)
+				}
+			}
+		}
+		fmt.Fprintf(&lcov, "BRF:%d\nBRH:%d\n", counts.Branches, counts.BranchesCovered)
+
+		hit := 0
+		for _, number := range sortedLines(lines) {
+			fmt.Fprintf(&lcov, "DA:%d,%d\n", number, lines[number].count)
+			if lines[number].count != 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(&lcov, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit)
+	}
+
+	_, err := io.WriteString(out, lcov.String())
+	return err
+}
@@ -0,0 +1,161 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bobik.squidwock.com/root/gal/genalpha/interpreter"
+	"bobik.squidwock.com/root/gal/genalpha/lexer"
+	"bobik.squidwock.com/root/gal/genalpha/parser"
+)
+
+const coveredSource = `lowkey main{}
+    fax i = 0
+    durin i < 2
+        i = i + 1
+    end
+    foreal i == 5
+        fax r = fire unused()
+    end
+    fax r = fire used()
+end
+
+lowkey used{}
+    rizzult 1
+end
+
+lowkey unused{}
+    rizzult 2
+end
+`
+
+func runCovered(t *testing.T, coverage *interpreter.Coverage, source string, filename string) {
+	ast := parser.Parse(lexer.Lex(source))
+	interpreterState := interpreter.NewInterpreter([]string{})
+	for _, module := range interpreter.DefaultModules() {
+		interpreterState.RegisterModule(module)
+	}
+	interpreterState.Coverage = coverage
+
+	interpreterState.Load(&ast, filename)
+	if code := interpreterState.Run(); code != 0 {
+		t.Fatalf("%s exited with %d", filename, code)
+	}
+}
+
+func TestCoverageLCOV(t *testing.T) {
+	coverage := interpreter.NewCoverage()
+	runCovered(t, coverage, coveredSource, "main.gal")
+
+	var lcov bytes.Buffer
+	if err := coverage.WriteLCOV(&lcov); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `TN:
+SF:main.gal
+FN:1,main
+FN:12,used
+FN:16,unused
+FNDA:1,main
+FNDA:1,used
+FNDA:0,unused
+FNF:3
+FNH:2
+BRDA:3,0,0,2
+BRDA:3,0,1,1
+BRDA:6,0,0,0
+BRDA:6,0,1,1
+BRF:4
+BRH:3
+DA:2,1
+DA:3,1
+DA:4,2
+DA:6,1
+DA:7,0
+DA:9,1
+DA:13,1
+DA:17,0
+LF:8
+LH:6
+end_of_record
+`
+	if lcov.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", lcov.String(), want)
+	}
+}
+
+func TestCoverageSummary(t *testing.T) {
+	coverage := interpreter.NewCoverage()
+	runCovered(t, coverage, coveredSource, "main.gal")
+
+	want := interpreter.CoverageCounts{
+		Statements: 8, StatementsCovered: 6,
+		Branches: 4, BranchesCovered: 3,
+		Functions: 3, FunctionsCovered: 2,
+	}
+	if counts := coverage.Counts(""); counts != want {
+		t.Errorf("got %+v, want %+v", counts, want)
+	}
+
+	var summary bytes.Buffer
+	coverage.WriteSummary(&summary)
+	for _, line := range []string{
+		"main.gal   75.0% (6/8)       75.0% (3/4)       66.7% (2/3)",
+		"total      75.0% (6/8)       75.0% (3/4)       66.7% (2/3)",
+	} {
+		if !strings.Contains(summary.String(), line) {
+			t.Errorf("summary does not have %q\n%s", line, summary.String())
+		}
+	}
+}
+
+// gal test runs every test in its own interpreter with the same coverage
+func TestCoverageAddsRuns(t *testing.T) {
+	coverage := interpreter.NewCoverage()
+	coverage.Ignore("ignored.gal")
+	runCovered(t, coverage, coveredSource, "main.gal")
+	runCovered(t, coverage, coveredSource, "main.gal")
+	runCovered(t, coverage, coveredSource, "ignored.gal")
+
+	if files := coverage.Files(); len(files) != 1 || files[0] != "main.gal" {
+		t.Errorf("covered %v", files)
+	}
+
+	var lcov bytes.Buffer
+	coverage.WriteLCOV(&lcov)
+	for _, line := range []string{"FNDA:2,main", "DA:4,4", "BRDA:3,0,0,4"} {
+		if !strings.Contains(lcov.String(), line+"\n") {
+			t.Errorf("expected %s in\n%s", line, lcov.String())
+		}
+	}
+}
+
+func TestCoverageHTML(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "main.gal")
+	if err := os.WriteFile(filename, []byte(coveredSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	coverage := interpreter.NewCoverage()
+	runCovered(t, coverage, coveredSource, filename)
+
+	var page bytes.Buffer
+	if err := coverage.WriteHTML(&page); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, row := range []string{
+		`<tr class="covered" title="run 2 times"><td class="number">4</td><td class="count">2</td><td class="code">        i = i &#43; 1</td></tr>`,
+		`<tr class="partial" title="run 1 times, condition was yay 0 and nay 1 times"><td class="number">6</td>`,
+		`<tr class="uncovered" title="not run"><td class="number">7</td>`,
+		`<tr class="" title=""><td class="number">10</td><td class="count"></td><td class="code">end</td></tr>`,
+	} {
+		if !strings.Contains(page.String(), row) {
+			t.Errorf("page does not have %s", row)
+		}
+	}
+}
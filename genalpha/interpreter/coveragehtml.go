@@ -0,0 +1,128 @@
+package interpreter
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+)
+
+var coverageTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gal coverage</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table.summary { border-collapse: collapse; margin-bottom: 2em; }
+table.summary td, table.summary th { padding: 0.2em 1em; text-align: right; }
+table.summary td:first-child, table.summary th:first-child { text-align: left; }
+pre { margin: 0; }
+table.source { border-collapse: collapse; font-family: monospace; width: 100%; margin-bottom: 2em; }
+table.source td { padding: 0 0.5em; white-space: pre; vertical-align: top; }
+td.number, td.count { color: #888; text-align: right; width: 1%; }
+tr.covered td.code { background: #dfd; }
+tr.uncovered td.code { background: #fdd; }
+tr.partial td.code { background: #ffd; }
+</style>
+</head>
+<body>
+<h1>gal coverage</h1>
+<table class="summary">
+<tr><th>file</th><th>statements</th><th>branches</th><th>functions</th></tr>
+{{range .Files}}<tr><td><a href="#{{.Name}}">{{.Name}}</a></td><td>{{.Statements}}</td><td>{{.Branches}}</td><td>{{.Functions}}</td></tr>
+{{end}}<tr><th>total</th><th>{{.Total.Statements}}</th><th>{{.Total.Branches}}</th><th>{{.Total.Functions}}</th></tr>
+</table>
+{{range .Files}}<h2 id="{{.Name}}">{{.Name}}</h2>
+<table class="source">
+{{range .Lines}}<tr class="{{.Class}}" title="{{.Title}}"><td class="number">{{.Number}}</td><td class="count">{{.Count}}</td><td class="code">{{.Code}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+type coverageSummaryRow struct {
+	Name       string
+	Statements string
+	Branches   string
+	Functions  string
+}
+
+type coverageHTMLFile struct {
+	coverageSummaryRow
+	Lines []coverageHTMLLine
+}
+
+type coverageHTMLLine struct {
+	Number int
+	Count  string
+	Code   string
+	Class  string // covered, uncovered, partial or empty for lines without statements
+	Title  string
+}
+
+func coverageSummary(name string, counts CoverageCounts) coverageSummaryRow {
+	format := func(covered int, total int) string {
+		return fmt.Sprintf("%s (%d/%d)", coveragePercent(covered, total), covered, total)
+	}
+
+	return coverageSummaryRow{
+		Name:       name,
+		Statements: format(counts.StatementsCovered, counts.Statements),
+		Branches:   format(counts.BranchesCovered, counts.Branches),
+		Functions:  format(counts.FunctionsCovered, counts.Functions),
+	}
+}
+
+// WriteHTML writes a page with the summary and the source of every covered
+// file, the lines are marked as run, not run or with a branch that was not taken
+func (coverage *Coverage) WriteHTML(out io.Writer) error {
+	page := struct {
+		Files []coverageHTMLFile
+		Total coverageSummaryRow
+	}{
+		Total: coverageSummary("total", coverage.Counts("")),
+	}
+
+	for _, filename := range coverage.Files() {
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+
+		file := coverageHTMLFile{
+			coverageSummaryRow: coverageSummary(filename, coverage.Counts(filename)),
+		}
+		lines := coverage.files[filename].lines()
+		for i, code := range strings.Split(strings.TrimSuffix(string(source), "\n"), "\n") {
+			htmlLine := coverageHTMLLine{
+				Number: i + 1,
+				Code:   code,
+			}
+
+			if line := lines[i+1]; line != nil {
+				htmlLine.Count = fmt.Sprint(line.count)
+				htmlLine.Class = "covered"
+				htmlLine.Title = fmt.Sprintf("run %d times", line.count)
+				if line.count == 0 {
+					htmlLine.Class = "uncovered"
+					htmlLine.Title = "not run"
+				}
+
+				for _, branches := range line.branches {
+					if line.count != 0 && (branches[0] == 0 || branches[1] == 0) {
+						htmlLine.Class = "partial"
+						htmlLine.Title += fmt.Sprintf(", condition was yay %d and nay %d times", branches[0], branches[1])
+					}
+				}
+			}
+
+			file.Lines = append(file.Lines, htmlLine)
+		}
+
+		page.Files = append(page.Files, file)
+	}
+
+	return coverageTemplate.Execute(out, page)
+}
@@ -0,0 +1,31 @@
+package interpreter
+
+import (
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// Debugger is told about every statement before the ast walker runs it, it
+// pauses the program by not returning. The bytecode vm does not call it.
+type Debugger interface {
+	Statement(interpreterState *InterpreterState, filename string, node genalphatypes.ASTNode)
+}
+
+func debugStatement(interpreterState *InterpreterState, node genalphatypes.ASTNode, filename string) {
+	if node.Line == 0 || node.Type == genalphatypes.ASTNodeTypeFunctionDeclaration {
+		return
+	}
+
+	interpreterState.Debugger.Statement(interpreterState, currentFilename(interpreterState, filename), node)
+}
+
+// FrameScope returns the local scope of the frame at index i of the call stack
+func (interpreterState *InterpreterState) FrameScope(i int) Scope {
+	// every call but main's pushes a scope along with its frame, so the frames
+	// above i pushed the scopes above the one of i
+	above := len(interpreterState.CallStack) - 1 - i
+	if above == 0 {
+		return interpreterState.LocalScope
+	}
+
+	return interpreterState.ScopeStack[len(interpreterState.ScopeStack)-above]
+}
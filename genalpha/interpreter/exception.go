@@ -0,0 +1,305 @@
+package interpreter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// CallFrame is an entry of the gal call stack, Line is the line of the function
+// that is being run, so for all but the innermost frame it is the call site
+type CallFrame struct {
+	Function string
+	Filename string // file the function is declared in
+	Line     int
+}
+
+func pushFrame(interpreterState *InterpreterState, function Function) {
+	if interpreterState.Profiler != nil {
+		interpreterState.Profiler.call(interpreterState, function)
+	}
+	if interpreterState.Coverage != nil {
+		interpreterState.Coverage.call(function)
+	}
+	interpreterState.CallStack = append(interpreterState.CallStack, CallFrame{
+		Function: function.Name,
+		Filename: function.Filename,
+	})
+}
+
+// records the line of the statement being run in the innermost frame
+func setLine(interpreterState *InterpreterState, line int) {
+	if line != 0 && len(interpreterState.CallStack) != 0 {
+		if interpreterState.Profiler != nil {
+			interpreterState.Profiler.tick(interpreterState)
+		}
+		interpreterState.CallStack[len(interpreterState.CallStack)-1].Line = line
+		if interpreterState.Profiler != nil {
+			interpreterState.Profiler.statement(interpreterState)
+		}
+	}
+}
+
+// statements run in functions are interpreted without the name of their file
+func currentFilename(interpreterState *InterpreterState, filename string) string {
+	if filename == "" && len(interpreterState.CallStack) != 0 {
+		return interpreterState.CallStack[len(interpreterState.CallStack)-1].Filename
+	}
+
+	return filename
+}
+
+func popFrame(interpreterState *InterpreterState) {
+	if interpreterState.Profiler != nil {
+		interpreterState.Profiler.tick(interpreterState)
+	}
+	interpreterState.CallStack = interpreterState.CallStack[:len(interpreterState.CallStack)-1]
+}
+
+func copyCallStack(interpreterState *InterpreterState) []CallFrame {
+	stack := make([]CallFrame, len(interpreterState.CallStack))
+	copy(stack, interpreterState.CallStack)
+	return stack
+}
+
+// errorVariable converts err to the gal error value bound by sus, the value of
+// the error is its message and err.message, err.kind and err.stack are set
+func errorVariable(err *RuntimeError) Variable {
+	stack := map[string]*Variable{}
+	for i := range err.Stack {
+		// innermost call first
+		frame := err.Stack[len(err.Stack)-1-i]
+		stack[fmt.Sprint(i)] = &Variable{
+			Type:  genalphatypes.ASTNodeTypeString,
+			Value: frame.Function,
+			Indecies: map[string]*Variable{
+				"file": {
+					Type:  genalphatypes.ASTNodeTypeString,
+					Value: frame.Filename,
+				},
+				"line": {
+					Type:  genalphatypes.ASTNodeTypeNumber,
+					Value: fmt.Sprint(frame.Line),
+				},
+			},
+		}
+	}
+
+	return Variable{
+		Type:  genalphatypes.ASTNodeTypeError,
+		Value: err.Message,
+		Indecies: map[string]*Variable{
+			"message": {
+				Type:  genalphatypes.ASTNodeTypeString,
+				Value: err.Message,
+			},
+			"kind": {
+				Type:  genalphatypes.ASTNodeTypeString,
+				Value: err.Kind,
+			},
+			"stack": {
+				Type:     genalphatypes.ASTNodeTypeArray,
+				Value:    fmt.Sprint(len(stack)),
+				Indecies: stack,
+			},
+		},
+	}
+}
+
+// errorFromVariable converts a yeeted value back to a RuntimeError, error values
+// keep their kind and stack so they can be rethrown, anything else becomes an
+// error of kind "error" with the value as message. The stack of new errors is
+// captured once they are caught, see protect.
+func errorFromVariable(variable Variable) *RuntimeError {
+	if variable.Type != genalphatypes.ASTNodeTypeError {
+		return &RuntimeError{
+			Kind:    ErrorKindError,
+			Message: variable.Value,
+		}
+	}
+
+	err := &RuntimeError{
+		Kind:    ErrorKindError,
+		Message: variable.Value,
+	}
+	if kind := variable.Indecies["kind"]; kind != nil {
+		err.Kind = kind.Value
+	}
+
+	stack := variable.Indecies["stack"]
+	if stack == nil || len(stack.Indecies) == 0 {
+		return err
+	}
+
+	for i := len(stack.Indecies) - 1; i >= 0; i-- {
+		frame := stack.Indecies[fmt.Sprint(i)]
+		if frame == nil {
+			continue
+		}
+
+		callFrame := CallFrame{
+			Function: frame.Value,
+		}
+		if file := frame.Indecies["file"]; file != nil {
+			callFrame.Filename = file.Value
+		}
+		if line := frame.Indecies["line"]; line != nil {
+			callFrame.Line, _ = strconv.Atoi(line.Value)
+		}
+		err.Stack = append(err.Stack, callFrame)
+	}
+
+	return err
+}
+
+// protect runs body and returns the RuntimeError it panicked with, the scopes
+// and the call stack are unwound to where they were before body was called.
+// Other panics, such as std.exit, are not errors of the gal program and are
+// passed on.
+func protect(interpreterState *InterpreterState, body func()) (err *RuntimeError) {
+	scopeDepth := len(interpreterState.ScopeStack)
+	localScope := interpreterState.LocalScope
+	callDepth := len(interpreterState.CallStack)
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		runtimeError, ok := r.(*RuntimeError)
+		if !ok {
+			panic(r)
+		}
+
+		if runtimeError.Stack == nil {
+			runtimeError.Stack = copyCallStack(interpreterState)
+		}
+
+		interpreterState.ScopeStack = interpreterState.ScopeStack[:scopeDepth]
+		interpreterState.LocalScope = localScope
+		interpreterState.CallStack = interpreterState.CallStack[:callDepth]
+		err = runtimeError
+	}()
+
+	body()
+	return nil
+}
+
+type tryBlock struct {
+	body        []genalphatypes.ASTNode
+	catch       *genalphatypes.ASTNode
+	catchBody   []genalphatypes.ASTNode
+	finally     *genalphatypes.ASTNode
+	finallyBody []genalphatypes.ASTNode
+}
+
+// splits the children of a try node at the sus and anyways markers
+func splitTry(node genalphatypes.ASTNode) tryBlock {
+	block := tryBlock{}
+	current := &block.body
+
+	for i, child := range node.Children {
+		switch child.Type {
+		case genalphatypes.ASTNodeTypeCatch:
+			block.catch = &node.Children[i]
+			current = &block.catchBody
+		case genalphatypes.ASTNodeTypeFinally:
+			block.finally = &node.Children[i]
+			current = &block.finallyBody
+		default:
+			*current = append(*current, child)
+		}
+	}
+
+	return block
+}
+
+func interpretStatements(interpreterState *InterpreterState, nodes []genalphatypes.ASTNode) Variable {
+	for _, instructionNode := range nodes {
+		variable := interpretNode(interpreterState, instructionNode, "")
+		if variable.Type != genalphatypes.ASTNodeTypeNone {
+			return variable
+		}
+	}
+
+	return Variable{
+		Type:  genalphatypes.ASTNodeTypeNone,
+		Value: "",
+	}
+}
+
+func interpretTry(interpreterState *InterpreterState, node genalphatypes.ASTNode) Variable {
+	block := splitTry(node)
+
+	result := Variable{
+		Type:  genalphatypes.ASTNodeTypeNone,
+		Value: "",
+	}
+
+	err := protect(interpreterState, func() {
+		result = interpretStatements(interpreterState, block.body)
+	})
+
+	if err != nil && block.catch != nil {
+		if block.catch.Value != "" {
+			value := errorVariable(err)
+			interpreterState.LocalScope.Variables[block.catch.Value] = &value
+		}
+
+		err = protect(interpreterState, func() {
+			result = interpretStatements(interpreterState, block.catchBody)
+		})
+	}
+
+	// a value returned from anyways wins over the error and the earlier result
+	if block.finally != nil {
+		variable := interpretStatements(interpreterState, block.finallyBody)
+		if variable.Type != genalphatypes.ASTNodeTypeNone {
+			return variable
+		}
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+func interpretThrow(interpreterState *InterpreterState, node genalphatypes.ASTNode) Variable {
+	if len(node.Children) != 1 {
+		panic(NewRuntimeError(ErrorKindRuntime, "yeet should be done with one argument, the error to throw, such as 'yeet \"something went wrong\"'"))
+	}
+
+	value := resolveExpression(interpreterState, node.Children[0])
+	panic(errorFromVariable(value))
+}
+
+// StackTrace returns the gal call stack at the point where the recovered value
+// was panicked with, call it before the interpreter is used again
+func (interpreterState *InterpreterState) StackTrace(recovered any) []CallFrame {
+	runtimeError, ok := recovered.(*RuntimeError)
+	if ok && runtimeError.Stack != nil {
+		return runtimeError.Stack
+	}
+
+	return copyCallStack(interpreterState)
+}
+
+// FormatStackTrace formats the frames innermost first, one frame per line
+func FormatStackTrace(frames []CallFrame) string {
+	var trace strings.Builder
+	for i := len(frames) - 1; i >= 0; i-- {
+		frame := frames[i]
+		trace.WriteString("    at " + frame.Function + " (" + frame.Filename)
+		if frame.Line != 0 {
+			trace.WriteString(":" + strconv.Itoa(frame.Line))
+		}
+		trace.WriteString(")\n")
+	}
+
+	return trace.String()
+}
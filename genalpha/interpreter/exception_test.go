@@ -0,0 +1,52 @@
+package interpreter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+	"bobik.squidwock.com/root/gal/genalpha/lexer"
+	"bobik.squidwock.com/root/gal/genalpha/parser"
+)
+
+// gyat only runs inside a function when it is added to the syntax tree, the
+// errors of the import are caught by tryna like any other
+func TestImportErrorsAreCaught(t *testing.T) {
+	ast := parser.Parse(lexer.Lex(`lowkey main{}
+    tryna
+        fire std.println("replaced by the import")
+    sus err
+        fire std.println(err)
+    end
+    tryna
+        fire std.println("replaced by the import")
+    sus err
+        fire std.println(err)
+    end
+    fire std.println("after")
+end
+`))
+
+	main := ast.Children[0]
+	for i, filename := range []string{"missing.gal", "broken.gal"} {
+		main.Children[i+1].Children[0] = genalphatypes.ASTNode{
+			Type: genalphatypes.ASTNodeTypeImport,
+			Children: []genalphatypes.ASTNode{
+				{Type: genalphatypes.ASTNodeTypeString, Value: filename},
+			},
+		}
+	}
+
+	r := compare(t, ast, func(dir string) {
+		broken := "lowkey helper{}\n    fire std.println(1\nend\n"
+		if err := os.WriteFile(filepath.Join(dir, "broken.gal"), []byte(broken), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	want := "Error opening file ./missing.gal\n./broken.gal: PARSER: Mismatched brackets\nafter\n"
+	if r.stdout != want || r.failure != "" {
+		t.Errorf("got %+v, want %q", r, want)
+	}
+}
@@ -0,0 +1,56 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// ExitError is panicked with by std.exit, it unwinds the interpreter so deferred
+// cleanup still runs but unlike a RuntimeError it can not be caught from gal
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return "exit status " + strconv.Itoa(e.Code)
+}
+
+// exitCode returns the exit status for a value returned from main or passed to
+// std.exit, numbers are the status, nuthin is success and anything else is
+// printed to stderr and fails
+func exitCode(interpreterState *InterpreterState, value Variable) int {
+	switch value.Type {
+	case genalphatypes.ASTNodeTypeNone:
+		return 0
+	case genalphatypes.ASTNodeTypeNumber:
+		number, err := strconv.ParseFloat(value.Value, 64)
+		if err == nil && number == math.Trunc(number) {
+			return int(number)
+		}
+	}
+
+	interpreterState.Stdout.Flush()
+	fmt.Fprintln(os.Stderr, value.Value)
+	return 1
+}
+
+// deferred by Run and RunBytecode, flushes the output and turns std.exit into
+// the exit code, other panics are passed on
+func (interpreterState *InterpreterState) recoverExit(code *int) {
+	r := recover()
+	interpreterState.Stdout.Flush()
+	if r == nil {
+		return
+	}
+
+	exitError, ok := r.(*ExitError)
+	if !ok {
+		panic(r)
+	}
+
+	*code = exitError.Code
+}
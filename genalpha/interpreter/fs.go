@@ -0,0 +1,490 @@
+package interpreter
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// a file opened with fs.open, read line by line with fs.read_line
+type openFile struct {
+	file   *os.File
+	reader *bufio.Reader
+}
+
+// FSModule returns the fs module for files and directories. Unlike std.read
+// and std.write its functions throw an error of kind "io" when they fail
+func FSModule() *NativeModule {
+	return &NativeModule{
+		Name: "fs",
+		Functions: map[string]NativeFunction{
+			"read": func(call *CallContext, args []Variable) (Variable, error) {
+				path, err := pathArguments(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				contents, err := os.ReadFile(path[0])
+				if err != nil {
+					return Variable{}, ioError(call, err)
+				}
+
+				return stringVariable(string(contents)), nil
+			},
+			"write": func(call *CallContext, args []Variable) (Variable, error) {
+				return writeFile(call, args, os.O_TRUNC)
+			},
+			"append": func(call *CallContext, args []Variable) (Variable, error) {
+				return writeFile(call, args, os.O_APPEND)
+			},
+			"exists": func(call *CallContext, args []Variable) (Variable, error) {
+				path, err := pathArguments(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				_, err = os.Stat(path[0])
+				return booleanVariable(err == nil), nil
+			},
+			// fs.stat(path) returns the name, size in bytes, modified time as a
+			// timestamp, permissions, mode such as -rw-r--r--, is_dir and is_file
+			"stat": func(call *CallContext, args []Variable) (Variable, error) {
+				path, err := pathArguments(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				info, err := os.Stat(path[0])
+				if err != nil {
+					return Variable{}, ioError(call, err)
+				}
+
+				return objectVariable(map[string]Variable{
+					"name":        stringVariable(info.Name()),
+					"size":        numberVariable(float64(info.Size())),
+					"modified":    numberVariable(timestamp(info.ModTime())),
+					"permissions": stringVariable("0" + strconv.FormatUint(uint64(info.Mode().Perm()), 8)),
+					"mode":        stringVariable(info.Mode().String()),
+					"is_dir":      booleanVariable(info.IsDir()),
+					"is_file":     booleanVariable(info.Mode().IsRegular()),
+				}), nil
+			},
+			// the names of the files and directories in the directory, sorted
+			"list": func(call *CallContext, args []Variable) (Variable, error) {
+				path, err := pathArguments(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				entries, err := os.ReadDir(path[0])
+				if err != nil {
+					return Variable{}, ioError(call, err)
+				}
+
+				names := []Variable{}
+				for _, entry := range entries {
+					names = append(names, stringVariable(entry.Name()))
+				}
+
+				return arrayVariable(names), nil
+			},
+			// the paths of everything below the directory, sorted, directories
+			// come before what is in them
+			"walk": func(call *CallContext, args []Variable) (Variable, error) {
+				path, err := pathArguments(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				paths := []Variable{}
+				err = filepath.WalkDir(path[0], func(walked string, entry fs.DirEntry, err error) error {
+					if err != nil {
+						return err
+					}
+					if walked != path[0] {
+						paths = append(paths, stringVariable(walked))
+					}
+					return nil
+				})
+				if err != nil {
+					return Variable{}, ioError(call, err)
+				}
+
+				return arrayVariable(paths), nil
+			},
+			// the paths matching the pattern sorted, * and ? do not match a /
+			// but ** matches any number of directories
+			"glob": func(call *CallContext, args []Variable) (Variable, error) {
+				pattern, err := pathArguments(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				matches, err := glob(pattern[0])
+				if err != nil {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, call.Name+": "+err.Error())
+				}
+
+				paths := []Variable{}
+				for _, match := range matches {
+					paths = append(paths, stringVariable(match))
+				}
+
+				return arrayVariable(paths), nil
+			},
+			// creates the directory and the ones it is in, like mkdir -p
+			"mkdir": func(call *CallContext, args []Variable) (Variable, error) {
+				path, err := pathArguments(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				if err := os.MkdirAll(path[0], 0o755); err != nil {
+					return Variable{}, ioError(call, err)
+				}
+
+				return noneVariable(), nil
+			},
+			// fs.remove(path, yay) also removes a directory with everything in it
+			"remove": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 2); err != nil {
+					return Variable{}, err
+				}
+				path, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				recursive := len(args) == 2 && args[1].Value == string(genalphatypes.KeywordTrue)
+				if recursive {
+					// RemoveAll does not fail for a path that does not exist
+					if _, err := os.Lstat(path); err != nil {
+						return Variable{}, ioError(call, err)
+					}
+					err = os.RemoveAll(path)
+				} else {
+					err = os.Remove(path)
+				}
+				if err != nil {
+					return Variable{}, ioError(call, err)
+				}
+
+				return noneVariable(), nil
+			},
+			"rename": func(call *CallContext, args []Variable) (Variable, error) {
+				paths, err := pathArguments(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				if err := os.Rename(paths[0], paths[1]); err != nil {
+					return Variable{}, ioError(call, err)
+				}
+
+				return noneVariable(), nil
+			},
+			// copies a file, or a directory with everything in it
+			"copy": func(call *CallContext, args []Variable) (Variable, error) {
+				paths, err := pathArguments(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				if err := copyPath(paths[0], paths[1]); err != nil {
+					return Variable{}, ioError(call, err)
+				}
+
+				return noneVariable(), nil
+			},
+			// fs.temp_file(pattern) creates an empty file in the temporary
+			// directory and returns its path, a * in the pattern is replaced by
+			// random characters
+			"temp_file": func(call *CallContext, args []Variable) (Variable, error) {
+				return createTemp(call, args, func(pattern string) (string, error) {
+					file, err := os.CreateTemp("", pattern)
+					if err != nil {
+						return "", err
+					}
+					return file.Name(), file.Close()
+				})
+			},
+			"temp_dir": func(call *CallContext, args []Variable) (Variable, error) {
+				return createTemp(call, args, func(pattern string) (string, error) {
+					return os.MkdirTemp("", pattern)
+				})
+			},
+			// fs.open(path) opens a file for reading it line by line with
+			// fs.read_line, which is better than fs.read for big files
+			"open": func(call *CallContext, args []Variable) (Variable, error) {
+				path, err := pathArguments(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				file, err := os.Open(path[0])
+				if err != nil {
+					return Variable{}, ioError(call, err)
+				}
+
+				interpreterState := call.Interpreter
+				if interpreterState.openFiles == nil {
+					interpreterState.openFiles = map[int]*openFile{}
+				}
+				interpreterState.lastFile++
+				interpreterState.openFiles[interpreterState.lastFile] = &openFile{
+					file:   file,
+					reader: bufio.NewReader(file),
+				}
+
+				return numberVariable(float64(interpreterState.lastFile)), nil
+			},
+			// the next line without its line ending, nuthin at the end of the file.
+			// An empty line is "" which is == nuthin, loops check the end with !==
+			"read_line": func(call *CallContext, args []Variable) (Variable, error) {
+				_, file, err := fileArgument(call, args)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				line, err := file.reader.ReadString('\n')
+				if err == io.EOF && line == "" {
+					return noneVariable(), nil
+				}
+				if err != nil && err != io.EOF {
+					return Variable{}, ioError(call, err)
+				}
+
+				line = strings.TrimSuffix(line, "\n")
+				return stringVariable(strings.TrimSuffix(line, "\r")), nil
+			},
+			"close": func(call *CallContext, args []Variable) (Variable, error) {
+				id, file, err := fileArgument(call, args)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				delete(call.Interpreter.openFiles, id)
+				if err := file.file.Close(); err != nil {
+					return Variable{}, ioError(call, err)
+				}
+
+				return noneVariable(), nil
+			},
+		},
+	}
+}
+
+func ioError(call *CallContext, err error) error {
+	return NewRuntimeError(ErrorKindIO, call.Name+": "+err.Error())
+}
+
+// checks that all n arguments are strings
+func pathArguments(call *CallContext, args []Variable, n int) ([]string, error) {
+	if err := expectArguments(call, args, n, n); err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, n)
+	for i := range paths {
+		path, err := stringArgument(call, args, i)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = path
+	}
+
+	return paths, nil
+}
+
+func writeFile(call *CallContext, args []Variable, flag int) (Variable, error) {
+	strs, err := pathArguments(call, args, 2)
+	if err != nil {
+		return Variable{}, err
+	}
+
+	file, err := os.OpenFile(strs[0], os.O_WRONLY|os.O_CREATE|flag, 0o644)
+	if err != nil {
+		return Variable{}, ioError(call, err)
+	}
+
+	_, err = file.WriteString(strs[1])
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Variable{}, ioError(call, err)
+	}
+
+	return noneVariable(), nil
+}
+
+func createTemp(call *CallContext, args []Variable, create func(string) (string, error)) (Variable, error) {
+	pattern := "gal-*"
+	if expectNoArguments(call, args) != nil {
+		patterns, err := pathArguments(call, args, 1)
+		if err != nil {
+			return Variable{}, err
+		}
+		pattern = patterns[0]
+	}
+
+	path, err := create(pattern)
+	if err != nil {
+		return Variable{}, ioError(call, err)
+	}
+
+	return stringVariable(path), nil
+}
+
+func fileArgument(call *CallContext, args []Variable) (int, *openFile, error) {
+	if err := expectArguments(call, args, 1, 1); err != nil {
+		return 0, nil, err
+	}
+	id, err := intArgument(call, args, 0)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	file := call.Interpreter.openFiles[id]
+	if file == nil {
+		return 0, nil, NewRuntimeError(ErrorKindArgument, call.Name+" expects a file opened with fs.open, got "+formatValue(args[0]))
+	}
+
+	return id, file, nil
+}
+
+func copyPath(source string, destination string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return copyFile(source, destination, info.Mode().Perm())
+	}
+
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, relative)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(source string, destination string, perm fs.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// like filepath.Glob, but a ** part of the pattern matches any number of
+// directories, so src/**/*.gal matches every gal file below src
+func glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	// walk from the directories before the first part with a wildcard
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	root := []string{}
+	for _, part := range parts {
+		if strings.ContainsAny(part, "*?[") {
+			break
+		}
+		root = append(root, part)
+	}
+	start := strings.Join(root, "/")
+	if start == "" {
+		start = "."
+		if strings.HasPrefix(pattern, "/") {
+			start = "/"
+		}
+	}
+
+	matches := []string{}
+	err := filepath.WalkDir(start, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// unreadable directories are skipped like filepath.Glob does
+			return nil
+		}
+
+		// the current directory is where a relative pattern starts, not a match
+		if path == "." {
+			return nil
+		}
+
+		matched, err := matchParts(parts, strings.Split(filepath.ToSlash(path), "/"))
+		if err != nil {
+			return err
+		}
+		if matched {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	sort.Strings(matches)
+
+	return matches, err
+}
+
+func matchParts(pattern []string, path []string) (bool, error) {
+	for len(pattern) != 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				matched, err := matchParts(pattern[1:], path[i:])
+				if matched || err != nil {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(path) == 0 {
+			return false, nil
+		}
+		matched, err := filepath.Match(pattern[0], path[0])
+		if !matched || err != nil {
+			return false, err
+		}
+
+		pattern = pattern[1:]
+		path = path[1:]
+	}
+
+	return len(path) == 0, nil
+}
@@ -0,0 +1,125 @@
+package interpreter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// creates the files, with their name as contents, in a temporary directory
+// that is the working directory during the test
+func chdirFiles(t *testing.T, names ...string) string {
+	dir := t.TempDir()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	return dir
+}
+
+func TestGlob(t *testing.T) {
+	dir := chdirFiles(t, "a.gal", "notes.txt", "src/b.gal", "src/lib/c.gal")
+
+	for pattern, want := range map[string][]string{
+		"*.gal":         {"a.gal"},
+		"**":            {"a.gal", "notes.txt", "src", "src/b.gal", "src/lib", "src/lib/c.gal"},
+		"**/*.gal":      {"a.gal", "src/b.gal", "src/lib/c.gal"},
+		"src/**":        {"src", "src/b.gal", "src/lib", "src/lib/c.gal"},
+		"src/**/c.gal":  {"src/lib/c.gal"},
+		"src/*/*.gal":   {"src/lib/c.gal"},
+		"missing/**":    {},
+		dir + "/**/c.*": {filepath.Join(dir, "src", "lib", "c.gal")},
+	} {
+		matches, err := callModule(NewInterpreter([]string{}), FSModule(), "glob", stringVariable(pattern))
+		if err != nil {
+			t.Errorf("%s: %v", pattern, err)
+			continue
+		}
+
+		got := []string{}
+		for _, member := range arrayMembers(matches) {
+			got = append(got, filepath.ToSlash(member.Value))
+		}
+		if strings.Join(got, " ") != filepath.ToSlash(strings.Join(want, " ")) {
+			t.Errorf("fs.glob(%q) = %v, want %v", pattern, got, want)
+		}
+	}
+
+	_, err := callModule(NewInterpreter([]string{}), FSModule(), "glob", stringVariable("[a"))
+	if runtimeError, ok := err.(*RuntimeError); !ok || runtimeError.Kind != ErrorKindArgument {
+		t.Errorf("expected an argument error for a malformed pattern, got %v", err)
+	}
+}
+
+// lines are returned without their line ending and nuthin at the end of the
+// file, an empty line is an empty string
+func TestReadLine(t *testing.T) {
+	chdirFiles(t)
+	if err := os.WriteFile("lines.txt", []byte("one\r\n\nlast"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	interpreterState := NewInterpreter([]string{})
+	module := FSModule()
+	file, err := callModule(interpreterState, module, "open", stringVariable("lines.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Variable{stringVariable("one"), stringVariable(""), stringVariable("last"), noneVariable(), noneVariable()}
+	for i, want := range want {
+		line, err := callModule(interpreterState, module, "read_line", file)
+		if err != nil || line.Type != want.Type || line.Value != want.Value {
+			t.Errorf("line %d is %+v, %v, want %+v", i+1, line, err, want)
+		}
+	}
+
+	if _, err := callModule(interpreterState, module, "close", file); err != nil {
+		t.Fatal(err)
+	}
+	_, err = callModule(interpreterState, module, "read_line", file)
+	if err == nil || err.Error() != "fs.read_line expects a file opened with fs.open, got 1" {
+		t.Errorf("reading a closed file: %v", err)
+	}
+}
+
+func TestFS(t *testing.T) {
+	chdirFiles(t, "dir/a.txt", "dir/sub/b.txt")
+	s := stringVariable
+	testModule(t, FSModule(), []moduleTest{
+		{function: "read", args: []Variable{s("dir/a.txt")}, want: "dir/a.txt"},
+		{function: "read", args: []Variable{s("missing.txt")}, err: ErrorKindIO},
+		{function: "read", args: []Variable{numberVariable(1)}, err: ErrorKindArgument, want: "fs.read expects a string as argument 1, got 1"},
+		{function: "write", args: []Variable{s("new.txt"), s("one")}, want: ""},
+		{function: "append", args: []Variable{s("new.txt"), s(" two")}, want: ""},
+		{function: "read", args: []Variable{s("new.txt")}, want: "one two"},
+		{function: "exists", args: []Variable{s("dir/sub")}, want: "yay"},
+		{function: "list", args: []Variable{s("dir")}, want: "2"},
+		{function: "walk", args: []Variable{s("dir")}, want: "3"},
+		{function: "copy", args: []Variable{s("dir"), s("copy")}, want: ""},
+		{function: "read", args: []Variable{s("copy/sub/b.txt")}, want: "dir/sub/b.txt"},
+		{function: "remove", args: []Variable{s("copy")}, err: ErrorKindIO},
+		{function: "remove", args: []Variable{s("copy"), booleanVariable(true)}, want: ""},
+		{function: "remove", args: []Variable{s("copy"), booleanVariable(true)}, err: ErrorKindIO},
+		{function: "rename", args: []Variable{s("new.txt"), s("renamed.txt")}, want: ""},
+		{function: "exists", args: []Variable{s("new.txt")}, want: "nay"},
+		{function: "mkdir", args: []Variable{s("made/deep/down")}, want: ""},
+		{function: "exists", args: []Variable{s("made/deep/down")}, want: "yay"},
+	})
+}
@@ -1,19 +1,19 @@
 package interpreter
 
 import (
-	"bufio"
-	"bytes"
-	"crypto/sha256"
-	"encoding/gob"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"math"
+	"math/rand/v2"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
-	"bobik.squidwock.com/root/gal/genalpha/lexer"
-	"bobik.squidwock.com/root/gal/genalpha/parser"
+	"bobik.squidwock.com/root/gal/genalpha/cache"
 	"bobik.squidwock.com/root/gal/genalpha/pkg"
 	"bobik.squidwock.com/root/gal/genalpha/utils"
 )
@@ -23,9 +23,11 @@ type Scope struct {
 }
 
 type Function struct {
-	Name string
-	Args []genalphatypes.ASTNode
-	Body []genalphatypes.ASTNode
+	Name     string
+	Filename string
+	Line     int
+	Args     []genalphatypes.ASTNode
+	Body     []genalphatypes.ASTNode
 }
 
 type Variable struct {
@@ -36,23 +38,67 @@ type Variable struct {
 
 type InterpreterState struct {
 	Functions map[string]Function
+	Modules   map[string]*NativeModule
 
 	ScopeStack  []Scope
 	LocalScope  Scope
 	GlobalScope Scope
 
 	ImportedFiles []string
+	CallStack     []CallFrame
+
+	Stdout   *Output
+	Debugger Debugger   // nil unless run by gal debug
+	Profiler *Profiler  // nil unless run with -profile
+	Coverage *Coverage  // nil unless run with -coverage
+	Tracer   *Tracer    // nil unless run with -trace
+	Random   *rand.Rand // used by the rand module, see Seed
+
+	vm *vm // set while running bytecode
+
+	openFiles map[int]*openFile // opened with fs.open, by the number fs.open returned
+	lastFile  int
+
+	regexps map[string]*regexp.Regexp // compiled by the re module, by their pattern, at most maxRegexps
+
+	interruptHandler string         // the function given to os.on_interrupt
+	interrupts       chan os.Signal // nil unless os.on_interrupt set a handler
+	interrupted      atomic.Bool    // ctrl+c was pressed and the handler did not run yet
 }
 
-func Interpret(ast *genalphatypes.ASTNode, args []string, filename string) {
-	interpreterState := InterpreterState{
+// DefaultModules returns the native modules available to every gal program run from the cli
+func DefaultModules() []*NativeModule {
+	return []*NativeModule{
+		StdModule(),
+		TermModule(),
+		TestModule(),
+		JSONModule(),
+		StrModule(),
+		MathModule(),
+		RandModule(),
+		TimeModule(),
+		FSModule(),
+		ProcessModule(),
+		OSModule(),
+		PathModule(),
+		ReModule(),
+	}
+}
+
+// NewInterpreter creates an interpreter without any native modules registered,
+// args are exposed to the main function as the args variable
+func NewInterpreter(args []string) *InterpreterState {
+	interpreterState := &InterpreterState{
 		Functions: map[string]Function{},
+		Modules:   map[string]*NativeModule{},
 		GlobalScope: Scope{
 			Variables: map[string]*Variable{},
 		},
 		LocalScope: Scope{
 			Variables: map[string]*Variable{},
 		},
+		Stdout: NewOutput(os.Stdout),
+		Random: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
 	}
 
 	interpreterState.LocalScope.Variables["args"] = &Variable{
@@ -67,29 +113,75 @@ func Interpret(ast *genalphatypes.ASTNode, args []string, filename string) {
 		}
 	}
 
+	return interpreterState
+}
+
+// Interpret runs the program and returns its exit code
+func Interpret(ast *genalphatypes.ASTNode, args []string, filename string) int {
+	interpreterState := NewInterpreter(args)
+	for _, module := range DefaultModules() {
+		interpreterState.RegisterModule(module)
+	}
+
+	interpreterState.Load(ast, filename)
+	return interpreterState.Run()
+}
+
+// Load declares the functions of the program and everything it imports
+func (interpreterState *InterpreterState) Load(ast *genalphatypes.ASTNode, filename string) {
 	if ast.Type != genalphatypes.ASTNodeTypeProgram {
 		panic("Invalid AST type, parent should be a program node")
 	}
 
+	if interpreterState.Coverage != nil {
+		interpreterState.Coverage.add(filename, *ast)
+	}
+
 	for _, child := range ast.Children {
-		interpretNode(&interpreterState, child, filename)
+		interpretNode(interpreterState, child, filename)
+	}
+}
+
+// Run executes the main function of the loaded program and returns the exit
+// code, which is the value returned from main or passed to std.exit
+func (interpreterState *InterpreterState) Run() int {
+	if interpreterState.Functions["main"].Name == "" {
+		panic("No main function found, please declare a main function with the name 'main'")
 	}
 
-	for _, function := range interpreterState.Functions {
-		if function.Name == "main" {
-			for _, instructionNode := range function.Body {
-				variable := interpretNode(&interpreterState, instructionNode, "")
-				if variable.Type != genalphatypes.ASTNodeTypeNone {
-					fmt.Println("Program exited with code:", variable.Value)
-					return
-				}
-			}
+	return interpreterState.RunFunction("main")
+}
 
-			return
+// RunFunction runs the named function the way main is run, its arguments are
+// not set and the exit code is returned
+func (interpreterState *InterpreterState) RunFunction(name string) (code int) {
+	function := interpreterState.Functions[name]
+	if function.Name == "" {
+		panic(NewRuntimeError(ErrorKindRuntime, "Function "+name+" not found"))
+	}
+
+	defer interpreterState.recoverExit(&code)
+
+	pushFrame(interpreterState, function)
+	if interpreterState.Tracer != nil {
+		traceCall(interpreterState, function, []Variable{})
+	}
+	for _, instructionNode := range function.Body {
+		variable := interpretNode(interpreterState, instructionNode, "")
+		if variable.Type != genalphatypes.ASTNodeTypeNone {
+			if interpreterState.Tracer != nil {
+				traceReturn(interpreterState, variable)
+			}
+			popFrame(interpreterState)
+			return exitCode(interpreterState, variable)
 		}
 	}
+	if interpreterState.Tracer != nil {
+		traceReturn(interpreterState, Variable{Type: genalphatypes.ASTNodeTypeNone})
+	}
+	popFrame(interpreterState)
 
-	panic("No main function found, please declare a main function with the name 'main'")
+	return 0
 }
 
 func newScope(interpreterState *InterpreterState, scope Scope) {
@@ -107,6 +199,20 @@ func popScope(interpreterState *InterpreterState) {
 }
 
 func interpretNode(interpreterState *InterpreterState, node genalphatypes.ASTNode, filename string) Variable {
+	setLine(interpreterState, node.Line)
+	if interpreterState.interrupted.Load() {
+		handleInterrupt(interpreterState)
+	}
+	if interpreterState.Debugger != nil {
+		debugStatement(interpreterState, node, filename)
+	}
+	if interpreterState.Coverage != nil {
+		coverStatement(interpreterState, node, filename)
+	}
+	if interpreterState.Tracer != nil && node.Line != 0 && node.Type != genalphatypes.ASTNodeTypeFunctionDeclaration {
+		traceStatement(interpreterState, filename, node.Line, node.Column)
+	}
+
 	switch node.Type {
 	case genalphatypes.ASTNodeTypeMemberAssignment:
 		interpretMemberAssignment(interpreterState, node)
@@ -115,7 +221,7 @@ func interpretNode(interpreterState *InterpreterState, node genalphatypes.ASTNod
 			Value: "",
 		}
 	case genalphatypes.ASTNodeTypeFunctionDeclaration:
-		interpretFunctionDeclaration(interpreterState, node)
+		interpretFunctionDeclaration(interpreterState, node, filename)
 		return Variable{
 			Type:  genalphatypes.ASTNodeTypeNone,
 			Value: "",
@@ -138,6 +244,10 @@ func interpretNode(interpreterState *InterpreterState, node genalphatypes.ASTNod
 		return interpretWhile(interpreterState, node)
 	case genalphatypes.ASTNodeTypeReturn:
 		return interpretReturn(interpreterState, node)
+	case genalphatypes.ASTNodeTypeTry:
+		return interpretTry(interpreterState, node)
+	case genalphatypes.ASTNodeTypeThrow:
+		return interpretThrow(interpreterState, node)
 	case genalphatypes.ASTNodeTypeImport:
 		interpretImport(interpreterState, node, filename)
 		return Variable{
@@ -157,7 +267,17 @@ func interpretNode(interpreterState *InterpreterState, node genalphatypes.ASTNod
 	}
 }
 
-func interpretFunctionDeclaration(interpreterState *InterpreterState, node genalphatypes.ASTNode) {
+func interpretFunctionDeclaration(interpreterState *InterpreterState, node genalphatypes.ASTNode, filename string) {
+	function := NewFunction(node, filename)
+	if interpreterState.Functions[function.Name].Name != "" {
+		panic("Function " + function.Name + " already declared")
+	}
+
+	interpreterState.Functions[function.Name] = function
+}
+
+// NewFunction returns the function declared by a lowkey node of the file
+func NewFunction(node genalphatypes.ASTNode, filename string) Function {
 	name := node.Children[0].Value
 	args := []genalphatypes.ASTNode{}
 	bodyStart := 0
@@ -171,17 +291,13 @@ func interpretFunctionDeclaration(interpreterState *InterpreterState, node genal
 
 	bodyStart++
 
-	function := Function{
-		Name: name,
-		Args: args,
-		Body: node.Children[bodyStart:],
+	return Function{
+		Name:     name,
+		Filename: filename,
+		Line:     node.Line,
+		Args:     args,
+		Body:     node.Children[bodyStart:],
 	}
-
-	if interpreterState.Functions[name].Name != "" {
-		panic("Function " + name + " already declared")
-	}
-
-	interpreterState.Functions[name] = function
 }
 
 func interpretMemberAssignment(interpreterState *InterpreterState, node genalphatypes.ASTNode) Variable {
@@ -194,6 +310,9 @@ func interpretMemberAssignment(interpreterState *InterpreterState, node genalpha
 		if value.Type == genalphatypes.ASTNodeTypeNone {
 			delete(variable.Indecies, index.Value)
 			interpreterState.LocalScope.Variables[name] = variable
+			if interpreterState.Tracer != nil {
+				traceAssign(interpreterState, name, &index, value)
+			}
 			return Variable{
 				Type:  genalphatypes.ASTNodeTypeNone,
 				Value: "",
@@ -211,6 +330,9 @@ func interpretMemberAssignment(interpreterState *InterpreterState, node genalpha
 		}
 
 		interpreterState.LocalScope.Variables[name] = variable
+		if interpreterState.Tracer != nil {
+			traceAssign(interpreterState, name, &index, value)
+		}
 
 		return Variable{
 			Type:  genalphatypes.ASTNodeTypeNone,
@@ -223,6 +345,9 @@ func interpretMemberAssignment(interpreterState *InterpreterState, node genalpha
 		if value.Type == genalphatypes.ASTNodeTypeNone {
 			delete(variable.Indecies, index.Value)
 			interpreterState.GlobalScope.Variables[name] = variable
+			if interpreterState.Tracer != nil {
+				traceAssign(interpreterState, name, &index, value)
+			}
 			return Variable{
 				Type:  genalphatypes.ASTNodeTypeNone,
 				Value: "",
@@ -240,6 +365,9 @@ func interpretMemberAssignment(interpreterState *InterpreterState, node genalpha
 		}
 
 		interpreterState.GlobalScope.Variables[name] = variable
+		if interpreterState.Tracer != nil {
+			traceAssign(interpreterState, name, &index, value)
+		}
 
 		return Variable{
 			Type:  genalphatypes.ASTNodeTypeNone,
@@ -329,13 +457,13 @@ func resolveMemberAccess(interpreterState *InterpreterState, node genalphatypes.
 		variable = interpreterState.GlobalScope.Variables[name]
 	}
 	if variable == nil {
-		panic("Variable " + name + " not found")
+		panic(NewRuntimeError(ErrorKindRuntime, "Variable "+name+" not found"))
 	}
 
 	index := resolveExpression(interpreterState, node.Children[1])
 	value := variable.Indecies[index.Value]
 	if value == nil {
-		value = variable.Indecies[fmt.Sprint(utils.ParseNumber(index.Value))]
+		value = variable.Indecies[formatNumber(parseNumber(index.Value))]
 		if value == nil {
 			return Variable{
 				Type:  genalphatypes.ASTNodeTypeNone,
@@ -355,7 +483,7 @@ func resolveIdentifier(interpreterState *InterpreterState, node genalphatypes.AS
 		variable = interpreterState.GlobalScope.Variables[name]
 	}
 	if variable == nil {
-		panic("Variable " + name + " not found")
+		panic(NewRuntimeError(ErrorKindRuntime, "Variable "+name+" not found"))
 	}
 
 	return *variable
@@ -372,7 +500,7 @@ func resolveBinaryOperation(interpreterState *InterpreterState, node genalphatyp
 		case genalphatypes.ASTNodeTypeNumber:
 			return Variable{
 				Type:  genalphatypes.ASTNodeTypeNumber,
-				Value: fmt.Sprint(utils.ParseNumber(left.Value) + utils.ParseNumber(right.Value)),
+				Value: formatNumber(parseNumber(left.Value) + parseNumber(right.Value)),
 			}
 		case genalphatypes.ASTNodeTypeString:
 			return Variable{
@@ -388,48 +516,48 @@ func resolveBinaryOperation(interpreterState *InterpreterState, node genalphatyp
 
 	case "-":
 		if left.Type != genalphatypes.ASTNodeTypeNumber {
-			panic("Invalid operand type for binary operation -")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation -"))
 		}
 
 		return Variable{
 			Type:  genalphatypes.ASTNodeTypeNumber,
-			Value: fmt.Sprint(utils.ParseNumber(left.Value) - utils.ParseNumber(right.Value)),
+			Value: formatNumber(parseNumber(left.Value) - parseNumber(right.Value)),
 		}
 	case "*":
 		if left.Type != genalphatypes.ASTNodeTypeNumber {
-			panic("Invalid operand type for binary operation *")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation *"))
 		}
 
 		return Variable{
 			Type:  genalphatypes.ASTNodeTypeNumber,
-			Value: fmt.Sprint(utils.ParseNumber(left.Value) * utils.ParseNumber(right.Value)),
+			Value: formatNumber(parseNumber(left.Value) * parseNumber(right.Value)),
 		}
 	case "**":
 		if left.Type != genalphatypes.ASTNodeTypeNumber {
-			panic("Invalid operand type for binary operation **")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation **"))
 		}
 
 		return Variable{
 			Type:  genalphatypes.ASTNodeTypeNumber,
-			Value: fmt.Sprint(math.Pow(utils.ParseNumber(left.Value), utils.ParseNumber(right.Value))),
+			Value: formatNumber(math.Pow(parseNumber(left.Value), parseNumber(right.Value))),
 		}
 	case "/":
 		if left.Type != genalphatypes.ASTNodeTypeNumber {
-			panic("Invalid operand type for binary operation /")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation /"))
 		}
 
 		return Variable{
 			Type:  genalphatypes.ASTNodeTypeNumber,
-			Value: fmt.Sprint(utils.ParseNumber(left.Value) / utils.ParseNumber(right.Value)),
+			Value: formatNumber(parseNumber(left.Value) / parseNumber(right.Value)),
 		}
 	case "%":
 		if left.Type != genalphatypes.ASTNodeTypeNumber {
-			panic("Invalid operand type for binary operation %")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation %"))
 		}
 
 		return Variable{
 			Type:  genalphatypes.ASTNodeTypeNumber,
-			Value: fmt.Sprint(int(utils.ParseNumber(left.Value)) % int(utils.ParseNumber(right.Value))),
+			Value: formatNumber(float64(int(parseNumber(left.Value)) % int(parseNumber(right.Value)))),
 		}
 	case "==":
 		value := string(genalphatypes.KeywordFalse)
@@ -487,11 +615,11 @@ func resolveBinaryOperation(interpreterState *InterpreterState, node genalphatyp
 		}
 	case ">":
 		if left.Type != genalphatypes.ASTNodeTypeNumber {
-			panic("Invalid operand type for binary operation >")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation >"))
 		}
 
 		value := string(genalphatypes.KeywordFalse)
-		if utils.ParseNumber(left.Value) > utils.ParseNumber(right.Value) {
+		if parseNumber(left.Value) > parseNumber(right.Value) {
 			value = string(genalphatypes.KeywordTrue)
 		}
 
@@ -501,11 +629,11 @@ func resolveBinaryOperation(interpreterState *InterpreterState, node genalphatyp
 		}
 	case "<":
 		if left.Type != genalphatypes.ASTNodeTypeNumber {
-			panic("Invalid operand type for binary operation <")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation <"))
 		}
 
 		value := string(genalphatypes.KeywordFalse)
-		if utils.ParseNumber(left.Value) < utils.ParseNumber(right.Value) {
+		if parseNumber(left.Value) < parseNumber(right.Value) {
 			value = string(genalphatypes.KeywordTrue)
 		}
 
@@ -515,11 +643,11 @@ func resolveBinaryOperation(interpreterState *InterpreterState, node genalphatyp
 		}
 	case ">=":
 		if left.Type != genalphatypes.ASTNodeTypeNumber {
-			panic("Invalid operand type for binary operation <")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation <"))
 		}
 
 		value := string(genalphatypes.KeywordFalse)
-		if utils.ParseNumber(left.Value) >= utils.ParseNumber(right.Value) {
+		if parseNumber(left.Value) >= parseNumber(right.Value) {
 			value = string(genalphatypes.KeywordTrue)
 		}
 
@@ -529,11 +657,11 @@ func resolveBinaryOperation(interpreterState *InterpreterState, node genalphatyp
 		}
 	case "<=":
 		if left.Type != genalphatypes.ASTNodeTypeNumber {
-			panic("Invalid operand type for binary operation <")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation <"))
 		}
 
 		value := string(genalphatypes.KeywordFalse)
-		if utils.ParseNumber(left.Value) <= utils.ParseNumber(right.Value) {
+		if parseNumber(left.Value) <= parseNumber(right.Value) {
 			value = string(genalphatypes.KeywordTrue)
 		}
 
@@ -543,7 +671,7 @@ func resolveBinaryOperation(interpreterState *InterpreterState, node genalphatyp
 		}
 	case "&&":
 		if left.Type != genalphatypes.ASTNodeTypeBoolean {
-			panic("Invalid operand type for binary operation &&")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation &&"))
 		}
 
 		value := string(genalphatypes.KeywordFalse)
@@ -557,7 +685,7 @@ func resolveBinaryOperation(interpreterState *InterpreterState, node genalphatyp
 		}
 	case "||":
 		if left.Type != genalphatypes.ASTNodeTypeBoolean {
-			panic("Invalid operand type for binary operation ||")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation ||"))
 		}
 
 		value := string(genalphatypes.KeywordFalse)
@@ -570,7 +698,7 @@ func resolveBinaryOperation(interpreterState *InterpreterState, node genalphatyp
 			Value: value,
 		}
 	default:
-		panic("Invalid binary operation " + node.Value)
+		panic(NewRuntimeError(ErrorKindRuntime, "Invalid binary operation "+node.Value))
 	}
 }
 
@@ -580,7 +708,7 @@ func resolveUnaryOperation(interpreterState *InterpreterState, node genalphatype
 	switch node.Value {
 	case "!":
 		if operand.Type != genalphatypes.ASTNodeTypeBoolean {
-			panic("Invalid operand type for unary operation !")
+			panic(NewRuntimeError(ErrorKindType, "Invalid operand type for unary operation !"))
 		}
 
 		value := string(genalphatypes.KeywordFalse)
@@ -593,16 +721,16 @@ func resolveUnaryOperation(interpreterState *InterpreterState, node genalphatype
 			Value: value,
 		}
 	default:
-		panic("Invalid unary operation " + node.Value)
+		panic(NewRuntimeError(ErrorKindRuntime, "Invalid unary operation "+node.Value))
 	}
 }
 
-func resolveStdFunctionCall(interpreterState *InterpreterState, node genalphatypes.ASTNode) Variable {
+func resolveNativeFunctionCall(interpreterState *InterpreterState, node genalphatypes.ASTNode) Variable {
 	name := node.Children[0].Value
 
-	stdFunction := STDFunctions[name] // from std.go
-	if stdFunction == nil {
-		panic("Function " + name + " not found")
+	module, nativeFunction := lookupNative(interpreterState, name)
+	if nativeFunction == nil {
+		panic(NewRuntimeError(ErrorKindRuntime, "Function "+name+" not found"))
 	}
 
 	args := []Variable{}
@@ -611,7 +739,18 @@ func resolveStdFunctionCall(interpreterState *InterpreterState, node genalphatyp
 		args = append(args, arg)
 	}
 
-	return stdFunction(args)
+	call := CallContext{
+		Interpreter: interpreterState,
+		Module:      module,
+		Name:        name,
+	}
+
+	result, err := callNative(&call, nativeFunction, args)
+	if err != nil {
+		panic(err)
+	}
+
+	return result
 }
 
 func resolveFunctionCall(interpreterState *InterpreterState, node genalphatypes.ASTNode) Variable {
@@ -619,12 +758,25 @@ func resolveFunctionCall(interpreterState *InterpreterState, node genalphatypes.
 
 	function := interpreterState.Functions[name]
 	if function.Name == "" {
-		return resolveStdFunctionCall(interpreterState, node)
+		return resolveNativeFunctionCall(interpreterState, node)
 	}
 
 	// todo check if actualy correct?
 	if len(function.Args) > len(node.Children)-1 {
-		panic("Invalid number of arguments for function " + name)
+		panic(NewRuntimeError(ErrorKindArgument, "Invalid number of arguments for function "+name))
+	}
+
+	args := []Variable{}
+	for i := range function.Args {
+		args = append(args, resolveExpression(interpreterState, node.Children[i+1]))
+	}
+
+	return callFunction(interpreterState, function, args)
+}
+
+func callFunction(interpreterState *InterpreterState, function Function, args []Variable) Variable {
+	if len(function.Args) > len(args) {
+		panic(NewRuntimeError(ErrorKindArgument, "Invalid number of arguments for function "+function.Name))
 	}
 
 	scope := Scope{
@@ -632,19 +784,31 @@ func resolveFunctionCall(interpreterState *InterpreterState, node genalphatypes.
 	}
 
 	for i, arg := range function.Args {
-		argValue := resolveExpression(interpreterState, node.Children[i+1])
+		argValue := args[i]
 		scope.Variables[arg.Value] = &argValue
 	}
 
+	pushFrame(interpreterState, function)
 	newScope(interpreterState, scope)
+	if interpreterState.Tracer != nil {
+		traceCall(interpreterState, function, args)
+	}
 	for _, instructionNode := range function.Body {
 		variable := interpretNode(interpreterState, instructionNode, "")
 		if variable.Type != genalphatypes.ASTNodeTypeNone {
+			if interpreterState.Tracer != nil {
+				traceReturn(interpreterState, variable)
+			}
 			popScope(interpreterState)
+			popFrame(interpreterState)
 			return variable
 		}
 	}
+	if interpreterState.Tracer != nil {
+		traceReturn(interpreterState, Variable{Type: genalphatypes.ASTNodeTypeNone})
+	}
 	popScope(interpreterState)
+	popFrame(interpreterState)
 
 	return Variable{
 		Type:  genalphatypes.ASTNodeTypeNone,
@@ -660,8 +824,9 @@ func interpretBlock(interpreterState *InterpreterState, node genalphatypes.ASTNo
 func interpretIf(interpreterState *InterpreterState, node genalphatypes.ASTNode) Variable {
 	condition := resolveExpression(interpreterState, node.Children[0])
 	if condition.Type != genalphatypes.ASTNodeTypeBoolean {
-		panic("Invalid condition type for if statement, got: " + condition.Value)
+		panic(NewRuntimeError(ErrorKindType, "Invalid condition type for if statement, got: "+condition.Value))
 	}
+	coverBranch(interpreterState, node, condition)
 
 	if condition.Value == string(genalphatypes.KeywordTrue) {
 		for _, instructionNode := range node.Children[1:] {
@@ -682,8 +847,9 @@ func interpretWhile(interpreterState *InterpreterState, node genalphatypes.ASTNo
 	for {
 		condition := resolveExpression(interpreterState, node.Children[0])
 		if condition.Type != genalphatypes.ASTNodeTypeBoolean {
-			panic("Invalid condition type for while statement")
+			panic(NewRuntimeError(ErrorKindType, "Invalid condition type for while statement"))
 		}
+		coverBranch(interpreterState, node, condition)
 
 		if condition.Value == string(genalphatypes.KeywordFalse) {
 			break
@@ -705,40 +871,28 @@ func interpretWhile(interpreterState *InterpreterState, node genalphatypes.ASTNo
 
 func interpretReturn(interpreterState *InterpreterState, node genalphatypes.ASTNode) Variable {
 	if len(node.Children) != 1 {
-		panic("return should be done with one argument, the value to return, such as 'rizzult \"returned value\"'")
+		panic(NewRuntimeError(ErrorKindRuntime, "return should be done with one argument, the value to return, such as 'rizzult \"returned value\"'"))
 	}
 
 	return resolveExpression(interpreterState, node.Children[0])
 }
 
+// errors are raised as a RuntimeError, so tryna can catch them when gyat runs
+// inside a function
 func interpretImport(interpreterState *InterpreterState, node genalphatypes.ASTNode, parentFilename string) Variable {
 	if len(node.Children) != 1 {
-		panic("import should be done with one argument, the file to import, such as 'gyat \"test.gal\"'")
+		panic(NewRuntimeError(ErrorKindArgument, "import should be done with one argument, the file to import, such as 'gyat \"test.gal\"'"))
 	}
 
 	filename := node.Children[0].Value
-	newFilename := parentFilename[:strings.LastIndex(parentFilename, "/")]
-
-	importedFilename := newFilename + "/" + filename
-	if !strings.HasSuffix(filename, ".gal") {
-		// this means we are importing a package
-		// check in the parent directory
-		// remove filename from parentFilename
-
-		importedFilename = newFilename + "/" + filename + "/__.gal"
-		if !utils.FileExists(importedFilename) {
-			// check the installed packages directory
-			importedFilename = pkg.GetInstalledPackagesDirectory() + filename + "/__.gal"
-			if !utils.FileExists(importedFilename) {
-				fmt.Println(importedFilename)
-				panic("Package " + filename + " not found" + " in " + newFilename)
-			}
-		}
+	importedFilename, err := ResolveImport(parentFilename, filename)
+	if err != nil {
+		panic(NewRuntimeError(ErrorKindIO, err.Error()))
 	}
 
 	isString := node.Children[0].Type == genalphatypes.ASTNodeTypeString
 	if !isString {
-		panic("import should be done with a string argument, the file to import, such as 'gyat \"test.gal\"'")
+		panic(NewRuntimeError(ErrorKindType, "import should be done with a string argument, the file to import, such as 'gyat \"test.gal\"'"))
 	}
 
 	for _, importedFile := range interpreterState.ImportedFiles {
@@ -750,8 +904,15 @@ func interpretImport(interpreterState *InterpreterState, node genalphatypes.ASTN
 		}
 	}
 
+	if !utils.FileExists(importedFilename) {
+		panic(NewRuntimeError(ErrorKindIO, "Error opening file "+importedFilename))
+	}
+
+	ast := loadImport(importedFilename)
 	interpreterState.ImportedFiles = append(interpreterState.ImportedFiles, importedFilename)
-	ast := loadAST(importedFilename)
+	if interpreterState.Coverage != nil {
+		interpreterState.Coverage.add(importedFilename, ast)
+	}
 	for _, child := range ast.Children {
 		interpretNode(interpreterState, child, importedFilename)
 	}
@@ -762,10 +923,66 @@ func interpretImport(interpreterState *InterpreterState, node genalphatypes.ASTN
 	}
 }
 
+// the lexer and parser panic with a string on a syntax error
+func loadImport(filename string) genalphatypes.ASTNode {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		message, ok := r.(string)
+		if !ok {
+			panic(r)
+		}
+		panic(NewRuntimeError(ErrorKindRuntime, filename+": "+message))
+	}()
+
+	return cache.LoadAST(filename)
+}
+
+// ResolveImport returns the file imported with gyat from parentFilename. Files
+// ending in .gal are relative to the importing file, anything else is a package
+// that is looked up next to the importing file and then in the installed packages
+func ResolveImport(parentFilename string, filename string) (string, error) {
+	directory := filepath.Dir(parentFilename)
+	if strings.HasSuffix(filename, ".gal") {
+		return LoadName(filepath.Join(directory, filename)), nil
+	}
+
+	importedFilename := filepath.Join(directory, filename, "__.gal")
+	if utils.FileExists(importedFilename) {
+		return LoadName(importedFilename), nil
+	}
+
+	importedFilename = filepath.Join(pkg.GetInstalledPackagesDirectory(), filename, "__.gal")
+	if utils.FileExists(importedFilename) {
+		return LoadName(importedFilename), nil
+	}
+
+	return "", errors.New("Package " + filename + " not found in " + directory)
+}
+
+// LoadName returns the name a file is loaded and imported under, the cleaned
+// path with its directory such as ./main.gal, so a file has the same name in
+// stack traces, coverage and breakpoints however it was given
+func LoadName(filename string) string {
+	directory, file := filepath.Split(filepath.Clean(filename))
+	if directory == "" {
+		directory = "." + string(filepath.Separator)
+	}
+
+	return directory + file
+}
+
 func interpretVariableDeclaration(interpreterState *InterpreterState, node genalphatypes.ASTNode) {
 	name := node.Children[0].Value
 	value := resolveExpression(interpreterState, node.Children[1])
 
+	if interpreterState.Tracer != nil {
+		traceAssign(interpreterState, name, nil, value)
+	}
+
 	if strings.HasPrefix(name, "GLOBAL_") {
 		interpreterState.GlobalScope.Variables[name] = &value
 		return
@@ -789,6 +1006,9 @@ func interpretVariableAssignment(interpreterState *InterpreterState, node genalp
 
 		value.Indecies = originalIndecies
 		interpreterState.LocalScope.Variables[name] = &value
+		if interpreterState.Tracer != nil {
+			traceAssign(interpreterState, name, nil, value)
+		}
 		return
 	}
 
@@ -804,122 +1024,32 @@ func interpretVariableAssignment(interpreterState *InterpreterState, node genalp
 
 		value.Indecies = originalIndecies
 		interpreterState.GlobalScope.Variables[name] = &value
+		if interpreterState.Tracer != nil {
+			traceAssign(interpreterState, name, nil, value)
+		}
 		return
 	}
 
-	panic("Variable " + name + " not found")
-}
-
-// returns the sha256 hash of the given ast
-func sha256Hash(content string) string {
-	shaBytes := sha256.Sum256([]byte(content))
-	return hex.EncodeToString(shaBytes[:])
-}
-
-func getNodeBytes(node genalphatypes.ASTNode) []byte {
-	var network bytes.Buffer
-	enc := gob.NewEncoder(&network)
-
-	err := enc.Encode(node)
-	if err != nil {
-		panic("Error encoding node")
-	}
-
-	return network.Bytes()
+	panic(NewRuntimeError(ErrorKindRuntime, "Variable "+name+" not found"))
 }
 
-func getNodeFromBytes(nodeBytes []byte) genalphatypes.ASTNode {
-	var node genalphatypes.ASTNode
-	dec := gob.NewDecoder(bytes.NewReader(nodeBytes))
-
-	err := dec.Decode(&node)
-	if err != nil {
-		panic(err)
-	}
-
-	return node
-}
-
-func readFirstLineFile(filename string) string {
-	file, err := os.Open(filename)
-	if err != nil {
-		return ""
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	if scanner.Scan() {
-		firstLine := scanner.Text()
-		return firstLine
-	}
-
-	return ""
-}
-
-func writeToFile(filename string, data string) {
-	file, err := os.Create(filename)
-	if err != nil {
-		panic(err)
-	}
-	defer file.Close()
-
-	_, err = file.WriteString(data)
+// like utils.ParseNumber but the error can be caught from gal
+func parseNumber(s string) float64 {
+	result, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		panic(err)
+		panic(NewRuntimeError(ErrorKindType, "Error parsing number "+s))
 	}
-}
 
-// get everything except the first line of the file
-func readNotFirstLineFile(filename string) string {
-	file, err := os.Open(filename)
-	if err != nil {
-		panic(err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	scanner.Scan()
-	scanner.Scan()
-	return scanner.Text()
+	return result
 }
 
-// load and save are for when importing new files so we dont have to lex and parse them again (optimization and easier to work with)
-func loadAST(filename string) genalphatypes.ASTNode {
-	firstLine := readFirstLineFile(filename + "+")
-	if firstLine == "" {
-		// parse here and then save the ast
-		contents := utils.ReadContents(filename)
-		tokens := lexer.Lex(contents)
-		ast := parser.Parse(tokens)
-		// saveAST(ast, filename, filename+"+")
-		return ast
+// numbers are written without an exponent unless they are huge, so timestamps
+// and sizes print as 1710072000 instead of 1.710072e+09. Every number a program
+// sees is formatted with this, == compares the formatted values
+func formatNumber(number float64) string {
+	if math.Abs(number) < 1e21 {
+		return strconv.FormatFloat(number, 'f', -1, 64)
 	}
 
-	node := readNotFirstLineFile(filename + "+")
-	node = strings.ReplaceAll(node, "\\n", "\n")
-
-	if len(node) == 0 {
-		panic("Error reading ast from file")
-	}
-
-	return getNodeFromBytes([]byte(node))
-}
-
-func saveAST(ast genalphatypes.ASTNode, sourceFilename string, filename string) {
-	// format:
-	// - sha256 hash of the ast
-	// - the rest of the ast in gob format
-
-	// first we need to get the sha256 hash of the ast
-	contents := utils.ReadContents(sourceFilename)
-	sha := sha256Hash(contents)
-	if readFirstLineFile(filename) == sha {
-		return
-	}
-
-	// replace any new lines with \\n
-	bytes := string(getNodeBytes(ast))
-	bytes = strings.ReplaceAll(bytes, "\n", "\\n")
-
-	writeToFile(filename, sha+"\n"+bytes)
+	return fmt.Sprint(number)
 }
@@ -0,0 +1,259 @@
+package interpreter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// JSONModule returns the json module, json.parse and json.stringify
+func JSONModule() *NativeModule {
+	return &NativeModule{
+		Name: "json",
+		Functions: map[string]NativeFunction{
+			"parse": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				source, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				return parseJSON(source)
+			},
+			// json.stringify(value, indent) indents with indent spaces if it is a
+			// number, with the string if it is one and with 2 spaces if it is yay
+			"stringify": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 2); err != nil {
+					return Variable{}, err
+				}
+
+				value, err := jsonValue(args[0], 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				// <, > and & are kept as they are instead of escaped for html
+				var buffer bytes.Buffer
+				encoder := json.NewEncoder(&buffer)
+				encoder.SetEscapeHTML(false)
+				encoder.Encode(value)
+				encoded := bytes.TrimSuffix(buffer.Bytes(), []byte("\n"))
+				if len(args) == 2 {
+					indent, err := jsonIndent(call, args, 1)
+					if err != nil {
+						return Variable{}, err
+					}
+
+					if indent != "" {
+						var indented bytes.Buffer
+						json.Indent(&indented, encoded, "", indent)
+						encoded = indented.Bytes()
+					}
+				}
+
+				return stringVariable(string(encoded)), nil
+			},
+		},
+	}
+}
+
+func parseJSON(source string) (Variable, error) {
+	decoder := json.NewDecoder(strings.NewReader(source))
+	decoder.UseNumber()
+
+	var value any
+	err := decoder.Decode(&value)
+	offset := decoder.InputOffset()
+	if err == nil {
+		// only whitespace may follow the value
+		_, err = decoder.Token()
+		if err == io.EOF {
+			return variableFromJSON(value), nil
+		}
+		if err == nil {
+			err = errors.New("unexpected data after the value")
+			offset += int64(len(source[offset:]) - len(strings.TrimLeft(source[offset:], " \t\r\n")))
+		}
+	}
+
+	// the offset of a syntax error is after the invalid character
+	var syntaxError *json.SyntaxError
+	if errors.As(err, &syntaxError) {
+		offset = syntaxError.Offset - 1
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errors.New("unexpected end of input")
+		offset = int64(len(source))
+	}
+
+	line := strings.Count(source[:offset], "\n") + 1
+	column := int(offset) - strings.LastIndex(source[:offset], "\n")
+	return Variable{}, NewRuntimeError(ErrorKindJSON, "json.parse: "+strings.TrimPrefix(err.Error(), "json: ")+" at line "+strconv.Itoa(line)+", column "+strconv.Itoa(column))
+}
+
+// objects are arrays with an empty value, so they can be told apart from
+// arrays when they are stringified again
+func variableFromJSON(value any) Variable {
+	switch value := value.(type) {
+	case map[string]any:
+		indecies := map[string]*Variable{}
+		for key, member := range value {
+			variable := variableFromJSON(member)
+			indecies[key] = &variable
+		}
+
+		return Variable{
+			Type:     genalphatypes.ASTNodeTypeArray,
+			Value:    "",
+			Indecies: indecies,
+		}
+	case []any:
+		indecies := map[string]*Variable{}
+		for i, member := range value {
+			variable := variableFromJSON(member)
+			indecies[strconv.Itoa(i)] = &variable
+		}
+
+		return Variable{
+			Type:     genalphatypes.ASTNodeTypeArray,
+			Value:    strconv.Itoa(len(value)),
+			Indecies: indecies,
+		}
+	case json.Number:
+		// formatted like the results of arithmetic, so 1e2 is 100 and 1.0 is 1,
+		// a number too large for a float is +Inf or -Inf
+		number, _ := value.Float64()
+		return numberVariable(number)
+	case string:
+		return Variable{
+			Type:  genalphatypes.ASTNodeTypeString,
+			Value: value,
+		}
+	case bool:
+		if value {
+			return Variable{
+				Type:  genalphatypes.ASTNodeTypeBoolean,
+				Value: string(genalphatypes.KeywordTrue),
+			}
+		}
+
+		return Variable{
+			Type:  genalphatypes.ASTNodeTypeBoolean,
+			Value: string(genalphatypes.KeywordFalse),
+		}
+	}
+
+	return Variable{
+		Type:  genalphatypes.ASTNodeTypeNone,
+		Value: "",
+	}
+}
+
+// a value with indecies 0 to n-1 is an array and any other value with
+// indecies an object, except for the objects made by json.parse
+func jsonValue(variable Variable, depth int) (any, error) {
+	if depth == 1000 {
+		return nil, NewRuntimeError(ErrorKindJSON, "json.stringify: value is nested too deep, does it contain itself?")
+	}
+
+	isObject := variable.Type == genalphatypes.ASTNodeTypeArray && variable.Value == ""
+	if len(variable.Indecies) != 0 || variable.Type == genalphatypes.ASTNodeTypeArray {
+		keys := []string{}
+		for key, member := range variable.Indecies {
+			if member != nil {
+				keys = append(keys, key)
+			}
+		}
+
+		if !isObject && isArrayKeys(keys) {
+			array := make([]any, len(keys))
+			for i := range array {
+				value, err := jsonValue(*variable.Indecies[strconv.Itoa(i)], depth+1)
+				if err != nil {
+					return nil, err
+				}
+				array[i] = value
+			}
+			return array, nil
+		}
+
+		object := map[string]any{}
+		for _, key := range keys {
+			value, err := jsonValue(*variable.Indecies[key], depth+1)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = value
+		}
+		return object, nil
+	}
+
+	switch variable.Type {
+	case genalphatypes.ASTNodeTypeNone:
+		return nil, nil
+	case genalphatypes.ASTNodeTypeBoolean:
+		return variable.Value == string(genalphatypes.KeywordTrue), nil
+	case genalphatypes.ASTNodeTypeNumber:
+		number, err := strconv.ParseFloat(variable.Value, 64)
+		if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+			return nil, NewRuntimeError(ErrorKindJSON, "json.stringify: "+variable.Value+" can not be represented in JSON")
+		}
+		return number, nil
+	}
+
+	return variable.Value, nil
+}
+
+func isArrayKeys(keys []string) bool {
+	numbers := []int{}
+	for _, key := range keys {
+		number, err := strconv.Atoi(key)
+		if err != nil || strconv.Itoa(number) != key {
+			return false
+		}
+		numbers = append(numbers, number)
+	}
+
+	sort.Ints(numbers)
+	for i, number := range numbers {
+		if i != number {
+			return false
+		}
+	}
+
+	return true
+}
+
+// the indent is argument i
+func jsonIndent(call *CallContext, args []Variable, i int) (string, error) {
+	switch args[i].Type {
+	case genalphatypes.ASTNodeTypeBoolean:
+		if args[i].Value == string(genalphatypes.KeywordTrue) {
+			return "  ", nil
+		}
+		return "", nil
+	case genalphatypes.ASTNodeTypeNumber:
+		spaces, err := intArgument(call, args, i)
+		if err != nil {
+			return "", err
+		}
+		if spaces < 0 {
+			return "", NewRuntimeError(ErrorKindArgument, fmt.Sprintf("%s expects a number of spaces that is not negative as argument %d, got %d", call.Name, i+1, spaces))
+		}
+		return strings.Repeat(" ", spaces), nil
+	case genalphatypes.ASTNodeTypeString, genalphatypes.ASTNodeTypeNone:
+		return args[i].Value, nil
+	}
+
+	return "", NewRuntimeError(ErrorKindArgument, fmt.Sprintf("%s expects yay, a number or a string as argument %d, got %s", call.Name, i+1, formatValue(args[i])))
+}
@@ -0,0 +1,70 @@
+package interpreter
+
+import (
+	"testing"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// numbers are formatted like the results of arithmetic, so they compare equal
+// to the numbers written in gal
+func TestParseJSONNumbers(t *testing.T) {
+	for source, want := range map[string]string{
+		"1e2":                  "100",
+		"1.0":                  "1",
+		"-0.50":                "-0.5",
+		"0.000001":             "0.000001",
+		"12345678901234567890": "12345678901234567000",
+		"1e21":                 "1e+21",
+		"1e400":                "+Inf",
+	} {
+		parsed, err := callModule(NewInterpreter([]string{}), JSONModule(), "parse", stringVariable(source))
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+		if parsed.Type != genalphatypes.ASTNodeTypeNumber || parsed.Value != want {
+			t.Errorf("%s is parsed as %+v, want %s", source, parsed, want)
+		}
+	}
+}
+
+func TestParseJSONErrors(t *testing.T) {
+	for source, want := range map[string]string{
+		"":                      "json.parse: unexpected end of input at line 1, column 1",
+		"[1, 2":                 "json.parse: unexpected end of input at line 1, column 6",
+		"{\"a\": 1,\n \"b\": }": "json.parse: invalid character '}' looking for beginning of value at line 2, column 7",
+		"x":                     "json.parse: invalid character 'x' looking for beginning of value at line 1, column 1",
+		"[1,,2]":                "json.parse: invalid character ',' looking for beginning of value at line 1, column 4",
+		"1 2":                   "json.parse: unexpected data after the value at line 1, column 3",
+	} {
+		_, err := callModule(NewInterpreter([]string{}), JSONModule(), "parse", stringVariable(source))
+		runtimeError, ok := err.(*RuntimeError)
+		if !ok || runtimeError.Kind != ErrorKindJSON || runtimeError.Message != want {
+			t.Errorf("%q: got %v, want %s", source, err, want)
+		}
+	}
+}
+
+// objects are stringified with their keys sorted, so a parsed value
+// stringifies to the same source
+func TestStringifyJSONRoundTrip(t *testing.T) {
+	for _, source := range []string{
+		`{"list":[1,2.5,"x <&>",true,null],"name":"gal","nested":{"empty":[],"object":{}}}`,
+		`[[1,2],{"0":"not an array"},-3]`,
+		`"text"`,
+		`100`,
+	} {
+		interpreterState := NewInterpreter([]string{})
+		parsed, err := callModule(interpreterState, JSONModule(), "parse", stringVariable(source))
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+
+		stringified, err := callModule(interpreterState, JSONModule(), "stringify", parsed)
+		if err != nil || stringified.Value != source {
+			t.Errorf("%s is stringified as %s, %v", source, stringified.Value, err)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package interpreter
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// MathModule returns the math module, rounding, trigonometry, logarithms and
+// the constants math.pi(), math.e() and math.inf()
+func MathModule() *NativeModule {
+	functions := map[string]NativeFunction{
+		"pi": func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectNoArguments(call, args); err != nil {
+				return Variable{}, err
+			}
+
+			return numberVariable(math.Pi), nil
+		},
+		"e": func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectNoArguments(call, args); err != nil {
+				return Variable{}, err
+			}
+
+			return numberVariable(math.E), nil
+		},
+		"inf": func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectNoArguments(call, args); err != nil {
+				return Variable{}, err
+			}
+
+			return numberVariable(math.Inf(1)), nil
+		},
+		"nan": func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectNoArguments(call, args); err != nil {
+				return Variable{}, err
+			}
+
+			return numberVariable(math.NaN()), nil
+		},
+		"is_nan": func(call *CallContext, args []Variable) (Variable, error) {
+			return testNumber(call, args, math.IsNaN)
+		},
+		"is_inf": func(call *CallContext, args []Variable) (Variable, error) {
+			return testNumber(call, args, func(x float64) bool {
+				return math.IsInf(x, 0)
+			})
+		},
+		"is_finite": func(call *CallContext, args []Variable) (Variable, error) {
+			return testNumber(call, args, func(x float64) bool {
+				return !math.IsInf(x, 0) && !math.IsNaN(x)
+			})
+		},
+		// math.round(x, decimals) rounds half away from zero to the number of
+		// decimals, to a whole number if decimals is not given
+		"round": func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectArguments(call, args, 1, 2); err != nil {
+				return Variable{}, err
+			}
+			x, err := numberArgument(call, args, 0)
+			if err != nil {
+				return Variable{}, err
+			}
+			if len(args) == 1 {
+				return numberVariable(math.Round(x)), nil
+			}
+
+			decimals, err := intArgument(call, args, 1)
+			if err != nil {
+				return Variable{}, err
+			}
+
+			// shifted by the decimals as written, so 1.005 rounds to 1.01 even
+			// though the closest float to it is a bit smaller
+			rounded := shiftDecimal(math.Round(shiftDecimal(x, decimals)), -decimals)
+			return numberVariable(rounded), nil
+		},
+		"clamp": func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectArguments(call, args, 3, 3); err != nil {
+				return Variable{}, err
+			}
+
+			numbers := make([]float64, 3)
+			for i := range numbers {
+				number, err := numberArgument(call, args, i)
+				if err != nil {
+					return Variable{}, err
+				}
+				numbers[i] = number
+			}
+			if numbers[1] > numbers[2] {
+				return Variable{}, NewRuntimeError(ErrorKindArgument, "math.clamp lower bound "+args[1].Value+" is greater than upper bound "+args[2].Value)
+			}
+
+			return numberVariable(math.Min(math.Max(numbers[0], numbers[1]), numbers[2])), nil
+		},
+		// math.min and math.max take numbers or a single array of numbers
+		"min": func(call *CallContext, args []Variable) (Variable, error) {
+			return reduceNumbers(call, args, math.Min)
+		},
+		"max": func(call *CallContext, args []Variable) (Variable, error) {
+			return reduceNumbers(call, args, math.Max)
+		},
+		// math.log(x, base) is the natural logarithm if base is not given
+		"log": func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectArguments(call, args, 1, 2); err != nil {
+				return Variable{}, err
+			}
+			x, err := numberArgument(call, args, 0)
+			if err != nil {
+				return Variable{}, err
+			}
+			if len(args) == 1 {
+				return numberVariable(math.Log(x)), nil
+			}
+
+			base, err := numberArgument(call, args, 1)
+			if err != nil {
+				return Variable{}, err
+			}
+			switch base {
+			case 2:
+				return numberVariable(math.Log2(x)), nil
+			case 10:
+				return numberVariable(math.Log10(x)), nil
+			}
+
+			return numberVariable(math.Log(x) / math.Log(base)), nil
+		},
+		"atan2": func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectArguments(call, args, 2, 2); err != nil {
+				return Variable{}, err
+			}
+			y, err := numberArgument(call, args, 0)
+			if err != nil {
+				return Variable{}, err
+			}
+			x, err := numberArgument(call, args, 1)
+			if err != nil {
+				return Variable{}, err
+			}
+
+			return numberVariable(math.Atan2(y, x)), nil
+		},
+		// math.parse_int(s, base) parses a whole number in a base from 2 to 36.
+		// Without a base it is 10, or taken from a 0x, 0o or 0b prefix
+		"parse_int": func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectArguments(call, args, 1, 2); err != nil {
+				return Variable{}, err
+			}
+			s, err := stringArgument(call, args, 0)
+			if err != nil {
+				return Variable{}, err
+			}
+
+			base := 0
+			if len(args) == 2 {
+				base, err = intArgument(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+				if base < 2 || base > 36 {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, "math.parse_int base must be between 2 and 36, got "+strconv.Itoa(base))
+				}
+			}
+
+			number, err := strconv.ParseInt(strings.TrimSpace(s), base, 64)
+			if err != nil {
+				return Variable{}, NewRuntimeError(ErrorKindType, "math.parse_int: "+strconv.Quote(s)+" is not a whole number")
+			}
+
+			return numberVariable(float64(number)), nil
+		},
+		// math.format_int(n, base) writes a whole number in a base from 2 to 36
+		"format_int": func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectArguments(call, args, 2, 2); err != nil {
+				return Variable{}, err
+			}
+			n, err := intArgument(call, args, 0)
+			if err != nil {
+				return Variable{}, err
+			}
+			base, err := intArgument(call, args, 1)
+			if err != nil {
+				return Variable{}, err
+			}
+			if base < 2 || base > 36 {
+				return Variable{}, NewRuntimeError(ErrorKindArgument, "math.format_int base must be between 2 and 36, got "+strconv.Itoa(base))
+			}
+
+			return stringVariable(strconv.FormatInt(int64(n), base)), nil
+		},
+	}
+
+	for name, function := range map[string]func(float64) float64{
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"trunc": math.Trunc,
+		"abs":   math.Abs,
+		"sign": func(x float64) float64 {
+			if x == 0 || math.IsNaN(x) {
+				return x
+			}
+			return math.Copysign(1, x)
+		},
+		"sqrt": math.Sqrt,
+		"exp":  math.Exp,
+		"sin":  math.Sin,
+		"cos":  math.Cos,
+		"tan":  math.Tan,
+		"asin": math.Asin,
+		"acos": math.Acos,
+		"atan": math.Atan,
+	} {
+		functions[name] = func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectArguments(call, args, 1, 1); err != nil {
+				return Variable{}, err
+			}
+			x, err := numberArgument(call, args, 0)
+			if err != nil {
+				return Variable{}, err
+			}
+
+			return numberVariable(function(x)), nil
+		}
+	}
+
+	return &NativeModule{
+		Name:      "math",
+		Functions: functions,
+	}
+}
+
+func testNumber(call *CallContext, args []Variable, test func(float64) bool) (Variable, error) {
+	if err := expectArguments(call, args, 1, 1); err != nil {
+		return Variable{}, err
+	}
+	x, err := numberArgument(call, args, 0)
+	if err != nil {
+		return Variable{}, err
+	}
+
+	return booleanVariable(test(x)), nil
+}
+
+func reduceNumbers(call *CallContext, args []Variable, reduce func(float64, float64) float64) (Variable, error) {
+	if len(args) == 1 && len(args[0].Indecies) != 0 {
+		numbers := []float64{}
+		for i, member := range arrayMembers(args[0]) {
+			number, err := strconv.ParseFloat(member.Value, 64)
+			if member.Type != genalphatypes.ASTNodeTypeNumber || err != nil {
+				return Variable{}, NewRuntimeError(ErrorKindArgument, call.Name+" expects an array of numbers, got "+formatValue(member)+" at index "+strconv.Itoa(i))
+			}
+			numbers = append(numbers, number)
+		}
+
+		return reduceSlice(call, numbers, reduce)
+	}
+
+	numbers := []float64{}
+	for i := range args {
+		number, err := numberArgument(call, args, i)
+		if err != nil {
+			return Variable{}, err
+		}
+		numbers = append(numbers, number)
+	}
+
+	return reduceSlice(call, numbers, reduce)
+}
+
+func reduceSlice(call *CallContext, numbers []float64, reduce func(float64, float64) float64) (Variable, error) {
+	if len(numbers) == 0 {
+		return Variable{}, NewRuntimeError(ErrorKindArgument, call.Name+" expects at least 1 number")
+	}
+
+	result := numbers[0]
+	for _, number := range numbers[1:] {
+		result = reduce(result, number)
+	}
+
+	return numberVariable(result), nil
+}
+
+// multiplies x by 10 to the power of places, by moving the point in its
+// shortest decimal representation instead of multiplying the float
+func shiftDecimal(x float64, places int) float64 {
+	if math.IsInf(x, 0) || math.IsNaN(x) || x == 0 {
+		return x
+	}
+
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(x, 'e', -1, 64), "e")
+	power, _ := strconv.Atoi(exponent)
+	shifted, _ := strconv.ParseFloat(mantissa+"e"+strconv.Itoa(power+places), 64)
+	return shifted
+}
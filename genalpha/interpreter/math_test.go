@@ -0,0 +1,45 @@
+package interpreter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMath(t *testing.T) {
+	s := stringVariable
+	n := numberVariable
+	numbers := arrayVariable([]Variable{n(3), n(-1), n(2)})
+	mixed := arrayVariable([]Variable{n(3), s("x")})
+	testModule(t, MathModule(), []moduleTest{
+		{function: "pi", args: []Variable{noneVariable()}, want: "3.141592653589793"},
+		{function: "pi", args: []Variable{n(1)}, err: ErrorKindArgument, want: "math.pi expects no arguments"},
+		{function: "inf", want: "+Inf"},
+		{function: "is_nan", args: []Variable{n(0)}, want: "nay"},
+		{function: "is_finite", args: []Variable{n(math.Inf(-1))}, want: "nay"},
+		{function: "round", args: []Variable{n(2.5)}, want: "3"},
+		{function: "round", args: []Variable{n(-2.5)}, want: "-3"},
+		{function: "round", args: []Variable{n(1.005), n(2)}, want: "1.01"},
+		{function: "round", args: []Variable{n(1234.5), n(-2)}, want: "1200"},
+		{function: "round", args: []Variable{n(1), n(0.5)}, err: ErrorKindArgument, want: "math.round expects a whole number as argument 2, got 0.5"},
+		{function: "clamp", args: []Variable{n(5), n(0), n(3)}, want: "3"},
+		{function: "clamp", args: []Variable{n(5), n(3), n(0)}, err: ErrorKindArgument, want: "math.clamp lower bound 3 is greater than upper bound 0"},
+		{function: "min", args: []Variable{n(3), n(-1), n(2)}, want: "-1"},
+		{function: "max", args: []Variable{numbers}, want: "3"},
+		{function: "max", args: []Variable{mixed}, err: ErrorKindArgument, want: `math.max expects an array of numbers, got "x" at index 1`},
+		{function: "min", err: ErrorKindArgument, want: "math.min expects at least 1 number"},
+		{function: "min", args: []Variable{n(1), s("2")}, err: ErrorKindArgument, want: `math.min expects a number as argument 2, got "2"`},
+		{function: "log", args: []Variable{n(8), n(2)}, want: "3"},
+		{function: "log", args: []Variable{n(1000), n(10)}, want: "3"},
+		{function: "log", args: []Variable{n(1)}, want: "0"},
+		{function: "sqrt", args: []Variable{n(-1)}, want: "NaN"},
+		{function: "sqrt", args: []Variable{s("4")}, err: ErrorKindArgument, want: `math.sqrt expects a number as argument 1, got "4"`},
+		{function: "sign", args: []Variable{n(-7)}, want: "-1"},
+		{function: "atan2", args: []Variable{n(0), n(-1)}, want: "3.141592653589793"},
+		{function: "parse_int", args: []Variable{s("0x1f")}, want: "31"},
+		{function: "parse_int", args: []Variable{s(" 101 "), n(2)}, want: "5"},
+		{function: "parse_int", args: []Variable{s("1.5")}, err: ErrorKindType, want: `math.parse_int: "1.5" is not a whole number`},
+		{function: "parse_int", args: []Variable{s("1"), n(1)}, err: ErrorKindArgument, want: "math.parse_int base must be between 2 and 36, got 1"},
+		{function: "format_int", args: []Variable{n(-255), n(16)}, want: "-ff"},
+		{function: "format_int", args: []Variable{n(35), n(36)}, want: "z"},
+	})
+}
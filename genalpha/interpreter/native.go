@@ -0,0 +1,289 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// NativeFunction is a function implemented in go and callable from gal code.
+// Returning a non nil error aborts the call with that error.
+type NativeFunction func(call *CallContext, args []Variable) (Variable, error)
+
+// NativeModule groups native functions under a common prefix, functions of
+// the module "std" are called from gal as std.<name>
+type NativeModule struct {
+	Name      string
+	Functions map[string]NativeFunction
+}
+
+// CallContext is passed to every native function call
+type CallContext struct {
+	Interpreter *InterpreterState
+	Module      *NativeModule
+	Name        string // full name as written in gal, for example std.print
+}
+
+const (
+	ErrorKindArgument  = "argument"
+	ErrorKindType      = "type"
+	ErrorKindIO        = "io"
+	ErrorKindRuntime   = "runtime"
+	ErrorKindIndex     = "index"
+	ErrorKindError     = "error" // thrown from gal with yeet
+	ErrorKindAssertion = "assertion"
+	ErrorKindJSON      = "json"
+	ErrorKindTime      = "time"
+	ErrorKindProcess   = "process"
+	ErrorKindRegex     = "regex"
+)
+
+// RuntimeError is the error returned by native functions, it is also what
+// runtime failures panic with so they can be caught with tryna/sus
+type RuntimeError struct {
+	Kind    string
+	Message string
+	Stack   []CallFrame // gal functions being called when the error happened, innermost last
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Message
+}
+
+func NewRuntimeError(kind string, message string) *RuntimeError {
+	return &RuntimeError{
+		Kind:    kind,
+		Message: message,
+	}
+}
+
+// registers the module with the interpreter, a module with the same name is replaced
+func (interpreterState *InterpreterState) RegisterModule(module *NativeModule) {
+	interpreterState.Modules[module.Name] = module
+}
+
+// registers a single function, the module is created if it does not exist yet
+func (interpreterState *InterpreterState) RegisterFunction(name string, function NativeFunction) {
+	moduleName, functionName := splitNativeName(name)
+	if moduleName == "" {
+		panic("Native function " + name + " must be namespaced, such as 'mymodule." + name + "'")
+	}
+
+	module := interpreterState.Modules[moduleName]
+	if module == nil {
+		module = &NativeModule{
+			Name:      moduleName,
+			Functions: map[string]NativeFunction{},
+		}
+		interpreterState.Modules[moduleName] = module
+	}
+
+	module.Functions[functionName] = function
+}
+
+// returns the full names of all registered native functions sorted alphabetically
+func (interpreterState *InterpreterState) NativeFunctionNames() []string {
+	names := []string{}
+	for _, module := range interpreterState.Modules {
+		for name := range module.Functions {
+			names = append(names, module.Name+"."+name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Call calls the gal function with the given name and returns its result
+func (call *CallContext) Call(name string, args ...Variable) (Variable, error) {
+	function := call.Interpreter.Functions[name]
+	if function.Name == "" {
+		return Variable{}, NewRuntimeError(ErrorKindRuntime, "Function "+name+" not found")
+	}
+
+	if call.Interpreter.vm != nil {
+		return call.Interpreter.vm.callFunction(name, args), nil
+	}
+
+	return callFunction(call.Interpreter, function, args), nil
+}
+
+// calls a native function, the error it returns and go runtime errors such as
+// an index out of range are converted to a RuntimeError so gal can catch them
+func callNative(call *CallContext, function NativeFunction, args []Variable) (result Variable, err *RuntimeError) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		goError, ok := r.(runtime.Error)
+		if !ok {
+			panic(r)
+		}
+
+		err = NewRuntimeError(ErrorKindRuntime, call.Name+": "+goError.Error())
+	}()
+
+	result, callError := function(call, args)
+	if callError == nil {
+		return result, nil
+	}
+
+	if !errors.As(callError, &err) {
+		err = NewRuntimeError(ErrorKindRuntime, callError.Error())
+	}
+
+	return result, err
+}
+
+func lookupNative(interpreterState *InterpreterState, name string) (*NativeModule, NativeFunction) {
+	moduleName, functionName := splitNativeName(name)
+	module := interpreterState.Modules[moduleName]
+	if module == nil {
+		return nil, nil
+	}
+
+	return module, module.Functions[functionName]
+}
+
+// std.print -> std, print
+func splitNativeName(name string) (string, string) {
+	i := strings.LastIndex(name, ".")
+	if i == -1 {
+		return "", name
+	}
+
+	return name[:i], name[i+1:]
+}
+
+// checks that the native function was called with min to max arguments
+func expectArguments(call *CallContext, args []Variable, min int, max int) error {
+	if len(args) >= min && len(args) <= max {
+		return nil
+	}
+
+	switch {
+	case min == max && min == 1:
+		return NewRuntimeError(ErrorKindArgument, call.Name+" expects exactly 1 argument")
+	case min == max:
+		return NewRuntimeError(ErrorKindArgument, fmt.Sprintf("%s expects exactly %d arguments", call.Name, min))
+	case max == min+1:
+		return NewRuntimeError(ErrorKindArgument, fmt.Sprintf("%s expects %d or %d arguments", call.Name, min, max))
+	}
+
+	return NewRuntimeError(ErrorKindArgument, fmt.Sprintf("%s expects %d to %d arguments", call.Name, min, max))
+}
+
+// fire f() passes a single empty argument, so it is allowed as well
+func expectNoArguments(call *CallContext, args []Variable) error {
+	if len(args) == 0 || len(args) == 1 && args[0].Type == genalphatypes.ASTNodeTypeNone {
+		return nil
+	}
+
+	return NewRuntimeError(ErrorKindArgument, call.Name+" expects no arguments")
+}
+
+func stringArgument(call *CallContext, args []Variable, i int) (string, error) {
+	if args[i].Type != genalphatypes.ASTNodeTypeString {
+		return "", NewRuntimeError(ErrorKindArgument, fmt.Sprintf("%s expects a string as argument %d, got %s", call.Name, i+1, formatValue(args[i])))
+	}
+
+	return args[i].Value, nil
+}
+
+func numberArgument(call *CallContext, args []Variable, i int) (float64, error) {
+	number, err := strconv.ParseFloat(args[i].Value, 64)
+	if args[i].Type != genalphatypes.ASTNodeTypeNumber || err != nil {
+		return 0, NewRuntimeError(ErrorKindArgument, fmt.Sprintf("%s expects a number as argument %d, got %s", call.Name, i+1, formatValue(args[i])))
+	}
+
+	return number, nil
+}
+
+func intArgument(call *CallContext, args []Variable, i int) (int, error) {
+	number, err := numberArgument(call, args, i)
+	if err != nil || number != math.Trunc(number) || math.Abs(number) > math.MaxInt32 {
+		return 0, NewRuntimeError(ErrorKindArgument, fmt.Sprintf("%s expects a whole number as argument %d, got %s", call.Name, i+1, formatValue(args[i])))
+	}
+
+	return int(number), nil
+}
+
+func stringVariable(value string) Variable {
+	return Variable{
+		Type:  genalphatypes.ASTNodeTypeString,
+		Value: value,
+	}
+}
+
+func numberVariable(value float64) Variable {
+	return Variable{
+		Type:  genalphatypes.ASTNodeTypeNumber,
+		Value: formatNumber(value),
+	}
+}
+
+func booleanVariable(value bool) Variable {
+	if value {
+		return Variable{
+			Type:  genalphatypes.ASTNodeTypeBoolean,
+			Value: string(genalphatypes.KeywordTrue),
+		}
+	}
+
+	return Variable{
+		Type:  genalphatypes.ASTNodeTypeBoolean,
+		Value: string(genalphatypes.KeywordFalse),
+	}
+}
+
+func noneVariable() Variable {
+	return Variable{
+		Type: genalphatypes.ASTNodeTypeNone,
+	}
+}
+
+// an array like the ones std.split returns, indecies 0 to n-1 and the length as value
+func arrayVariable(values []Variable) Variable {
+	indecies := map[string]*Variable{}
+	for i := range values {
+		indecies[strconv.Itoa(i)] = &values[i]
+	}
+
+	return Variable{
+		Type:     genalphatypes.ASTNodeTypeArray,
+		Value:    strconv.Itoa(len(values)),
+		Indecies: indecies,
+	}
+}
+
+// a value with an index for every member, json.stringify writes it as an object
+func objectVariable(members map[string]Variable) Variable {
+	indecies := map[string]*Variable{}
+	for key, member := range members {
+		indecies[key] = &member
+	}
+
+	return Variable{
+		Type:     genalphatypes.ASTNodeTypeArray,
+		Value:    "",
+		Indecies: indecies,
+	}
+}
+
+// the members of an array at the indecies 0, 1, ... up to the first one missing
+func arrayMembers(array Variable) []Variable {
+	members := []Variable{}
+	for i := 0; array.Indecies[strconv.Itoa(i)] != nil; i++ {
+		members = append(members, *array.Indecies[strconv.Itoa(i)])
+	}
+
+	return members
+}
@@ -0,0 +1,202 @@
+package interpreter
+
+import (
+	"os"
+	"os/signal"
+	"runtime"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// OSModule returns the os module with environment variables, the working
+// directory, information about the system and os.on_interrupt
+func OSModule() *NativeModule {
+	return &NativeModule{
+		Name: "os",
+		Functions: map[string]NativeFunction{
+			// os.env(name, default) returns the variable, or default if it is
+			// not set, nuthin without a default
+			"env": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 2); err != nil {
+					return Variable{}, err
+				}
+				name, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				value, ok := os.LookupEnv(name)
+				if ok {
+					return stringVariable(value), nil
+				}
+				if len(args) == 2 {
+					return args[1], nil
+				}
+
+				return noneVariable(), nil
+			},
+			"set_env": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 2, 2); err != nil {
+					return Variable{}, err
+				}
+				name, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				if err := os.Setenv(name, args[1].Value); err != nil {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, call.Name+": "+err.Error())
+				}
+
+				return noneVariable(), nil
+			},
+			"unset_env": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				name, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				os.Unsetenv(name)
+				return noneVariable(), nil
+			},
+			// all environment variables by name
+			"environ": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectNoArguments(call, args); err != nil {
+					return Variable{}, err
+				}
+
+				variables := map[string]Variable{}
+				for _, variable := range os.Environ() {
+					for i := 1; i < len(variable); i++ {
+						// names on windows can start with =
+						if variable[i] == '=' {
+							variables[variable[:i]] = stringVariable(variable[i+1:])
+							break
+						}
+					}
+				}
+
+				return objectVariable(variables), nil
+			},
+			"cwd": func(call *CallContext, args []Variable) (Variable, error) {
+				return osString(call, args, os.Getwd)
+			},
+			"chdir": func(call *CallContext, args []Variable) (Variable, error) {
+				path, err := pathArguments(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				if err := os.Chdir(path[0]); err != nil {
+					return Variable{}, ioError(call, err)
+				}
+
+				return noneVariable(), nil
+			},
+			"hostname": func(call *CallContext, args []Variable) (Variable, error) {
+				return osString(call, args, os.Hostname)
+			},
+			"home": func(call *CallContext, args []Variable) (Variable, error) {
+				return osString(call, args, os.UserHomeDir)
+			},
+			"temp_dir": func(call *CallContext, args []Variable) (Variable, error) {
+				return osString(call, args, func() (string, error) {
+					return os.TempDir(), nil
+				})
+			},
+			"pid": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectNoArguments(call, args); err != nil {
+					return Variable{}, err
+				}
+
+				return numberVariable(float64(os.Getpid())), nil
+			},
+			// the names go uses, such as linux, darwin or windows
+			"name": func(call *CallContext, args []Variable) (Variable, error) {
+				return osString(call, args, func() (string, error) {
+					return runtime.GOOS, nil
+				})
+			},
+			// such as amd64 or arm64
+			"arch": func(call *CallContext, args []Variable) (Variable, error) {
+				return osString(call, args, func() (string, error) {
+					return runtime.GOARCH, nil
+				})
+			},
+			// os.on_interrupt("function") calls the gal function when ctrl+c is
+			// pressed instead of stopping the program, os.on_interrupt(nuthin)
+			// stops it again
+			"on_interrupt": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+
+				interpreterState := call.Interpreter
+				if args[0].Type == genalphatypes.ASTNodeTypeNone {
+					if interpreterState.interrupts != nil {
+						signal.Stop(interpreterState.interrupts)
+						close(interpreterState.interrupts)
+						interpreterState.interrupts = nil
+					}
+					interpreterState.interruptHandler = ""
+					return noneVariable(), nil
+				}
+
+				name, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+				if interpreterState.Functions[name].Name == "" {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, "os.on_interrupt expects the name of a gal function, "+name+" not found")
+				}
+
+				interpreterState.interruptHandler = name
+				if interpreterState.interrupts == nil {
+					interrupts := make(chan os.Signal, 1)
+					signal.Notify(interrupts, os.Interrupt)
+					go func() {
+						for range interrupts {
+							interpreterState.interrupted.Store(true)
+						}
+					}()
+					interpreterState.interrupts = interrupts
+				}
+
+				return noneVariable(), nil
+			},
+		},
+	}
+}
+
+func osString(call *CallContext, args []Variable, get func() (string, error)) (Variable, error) {
+	if err := expectNoArguments(call, args); err != nil {
+		return Variable{}, err
+	}
+
+	value, err := get()
+	if err != nil {
+		return Variable{}, ioError(call, err)
+	}
+
+	return stringVariable(value), nil
+}
+
+// runs the function given to os.on_interrupt, called before the next statement
+// after ctrl+c was pressed so the program is never interrupted halfway through one
+func handleInterrupt(interpreterState *InterpreterState) {
+	interpreterState.interrupted.Store(false)
+
+	name := interpreterState.interruptHandler
+	if name == "" {
+		return
+	}
+	if interpreterState.vm != nil {
+		interpreterState.vm.callFunction(name, nil)
+		return
+	}
+
+	callFunction(interpreterState, interpreterState.Functions[name], nil)
+}
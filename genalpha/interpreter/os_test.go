@@ -0,0 +1,90 @@
+package interpreter
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"bobik.squidwock.com/root/gal/genalpha/lexer"
+	"bobik.squidwock.com/root/gal/genalpha/parser"
+)
+
+func TestOSEnv(t *testing.T) {
+	t.Setenv("GAL_SET", "value")
+	t.Setenv("GAL_CHANGED", "before")
+	os.Unsetenv("GAL_UNSET")
+
+	s := stringVariable
+	testModule(t, OSModule(), []moduleTest{
+		{function: "env", args: []Variable{s("GAL_SET")}, want: "value"},
+		{function: "env", args: []Variable{s("GAL_UNSET"), s("default")}, want: "default"},
+		{function: "env", args: []Variable{s("GAL_UNSET")}, want: ""},
+		{function: "env", args: []Variable{numberVariable(1)}, err: ErrorKindArgument, want: "os.env expects a string as argument 1, got 1"},
+		{function: "set_env", args: []Variable{s("GAL_CHANGED"), numberVariable(2)}, want: ""},
+		{function: "env", args: []Variable{s("GAL_CHANGED")}, want: "2"},
+		{function: "unset_env", args: []Variable{s("GAL_CHANGED")}, want: ""},
+		{function: "env", args: []Variable{s("GAL_CHANGED")}, want: ""},
+		{function: "cwd", args: []Variable{s("x")}, err: ErrorKindArgument, want: "os.cwd expects no arguments"},
+		{function: "name", want: runtime.GOOS},
+		{function: "arch", args: []Variable{noneVariable()}, want: runtime.GOARCH},
+	})
+
+	environ, err := callModule(NewInterpreter([]string{}), OSModule(), "environ")
+	if err != nil || environ.Indecies["GAL_SET"] == nil || environ.Indecies["GAL_SET"].Value != "value" {
+		t.Errorf("os.environ() returned %+v, %v", environ.Indecies["GAL_SET"], err)
+	}
+}
+
+// ctrl+c calls the handler before the next statement, os.on_interrupt(nuthin)
+// removes it so ctrl+c stops the program again
+func TestOnInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("a process can not send itself an interrupt on windows")
+	}
+
+	ast := parser.Parse(lexer.Lex("lowkey stop{}\n    fax GLOBAL_stopped = yay\nend\n"))
+	interpreterState := NewInterpreter([]string{})
+	interpreterState.Load(&ast, "./main.gal")
+	module := OSModule()
+
+	if _, err := callModule(interpreterState, module, "on_interrupt", stringVariable("stop")); err != nil {
+		t.Fatal(err)
+	}
+
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	for start := time.Now(); !interpreterState.interrupted.Load(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the interrupt did not arrive")
+		}
+	}
+
+	handleInterrupt(interpreterState)
+	if stopped := interpreterState.GlobalScope.Variables["GLOBAL_stopped"]; stopped == nil || stopped.Value != "yay" {
+		t.Errorf("the handler did not run, GLOBAL_stopped is %+v", stopped)
+	}
+	if interpreterState.interrupted.Load() {
+		t.Error("the interrupt is still pending after the handler ran")
+	}
+
+	// removing the handler twice does nothing the second time
+	for i := 0; i < 2; i++ {
+		if _, err := callModule(interpreterState, module, "on_interrupt", noneVariable()); err != nil {
+			t.Fatal(err)
+		}
+		if interpreterState.interrupts != nil || interpreterState.interruptHandler != "" {
+			t.Fatal("the handler is still set")
+		}
+	}
+
+	testModule(t, module, []moduleTest{
+		{function: "on_interrupt", args: []Variable{stringVariable("missing")}, err: ErrorKindArgument, want: "os.on_interrupt expects the name of a gal function, missing not found"},
+		{function: "on_interrupt", args: []Variable{numberVariable(1)}, err: ErrorKindArgument, want: "os.on_interrupt expects a string as argument 1, got 1"},
+	})
+}
@@ -0,0 +1,46 @@
+package interpreter
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// Output is the stdout of gal programs, writes are buffered unless stdout is a
+// terminal so printing in a loop that is piped to another program stays fast
+type Output struct {
+	writer      *bufio.Writer
+	interactive bool
+}
+
+func NewOutput(file *os.File) *Output {
+	return &Output{
+		writer:      bufio.NewWriter(file),
+		interactive: term.IsTerminal(int(file.Fd())),
+	}
+}
+
+// NewWriterOutput returns an output that is not buffered and writes to writer
+func NewWriterOutput(writer io.Writer) *Output {
+	return &Output{
+		writer:      bufio.NewWriter(writer),
+		interactive: true,
+	}
+}
+
+func (output *Output) Write(p []byte) (int, error) {
+	n, err := output.writer.Write(p)
+	if err != nil || !output.interactive {
+		return n, err
+	}
+
+	return n, output.writer.Flush()
+}
+
+// Flush writes the buffered output, it has to be called before reading input
+// and before the program exits
+func (output *Output) Flush() error {
+	return output.writer.Flush()
+}
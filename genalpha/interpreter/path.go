@@ -0,0 +1,128 @@
+package interpreter
+
+import (
+	"path/filepath"
+	"strconv"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// PathModule returns the path module for building and taking apart file paths
+// with the separator of the system, / or \ on windows. A trailing separator
+// does not change the result, path.dir("a/b/") is a just like path.dir("a/b")
+func PathModule() *NativeModule {
+	functions := map[string]NativeFunction{
+		// path.join("a", "b", "c.txt") or path.join(parts) joins the parts
+		// with the separator and cleans the result
+		"join": func(call *CallContext, args []Variable) (Variable, error) {
+			parts := []string{}
+			if len(args) == 1 && len(args[0].Indecies) != 0 {
+				for i, member := range arrayMembers(args[0]) {
+					if member.Type != genalphatypes.ASTNodeTypeString {
+						return Variable{}, NewRuntimeError(ErrorKindArgument, "path.join expects an array of strings, got "+formatValue(member)+" at index "+strconv.Itoa(i))
+					}
+					parts = append(parts, member.Value)
+				}
+
+				return stringVariable(filepath.Join(parts...)), nil
+			}
+
+			for i := range args {
+				part, err := stringArgument(call, args, i)
+				if err != nil {
+					return Variable{}, err
+				}
+				parts = append(parts, part)
+			}
+
+			return stringVariable(filepath.Join(parts...)), nil
+		},
+		// path.split(p) returns the directory and the last part, the same as
+		// path.dir(p) and path.base(p)
+		"split": func(call *CallContext, args []Variable) (Variable, error) {
+			paths, err := pathArguments(call, args, 1)
+			if err != nil {
+				return Variable{}, err
+			}
+
+			return arrayVariable([]Variable{
+				stringVariable(cleanDir(paths[0])),
+				stringVariable(filepath.Base(paths[0])),
+			}), nil
+		},
+		// the absolute path, relative paths are relative to the working directory
+		"abs": func(call *CallContext, args []Variable) (Variable, error) {
+			paths, err := pathArguments(call, args, 1)
+			if err != nil {
+				return Variable{}, err
+			}
+
+			abs, err := filepath.Abs(paths[0])
+			if err != nil {
+				return Variable{}, ioError(call, err)
+			}
+
+			return stringVariable(abs), nil
+		},
+		// path.relative(base, target) returns target relative to base, so
+		// path.join(base, path.relative(base, target)) is target
+		"relative": func(call *CallContext, args []Variable) (Variable, error) {
+			paths, err := pathArguments(call, args, 2)
+			if err != nil {
+				return Variable{}, err
+			}
+
+			relative, err := filepath.Rel(paths[0], paths[1])
+			if err != nil {
+				return Variable{}, NewRuntimeError(ErrorKindArgument, call.Name+": "+err.Error())
+			}
+
+			return stringVariable(relative), nil
+		},
+		"is_abs": func(call *CallContext, args []Variable) (Variable, error) {
+			paths, err := pathArguments(call, args, 1)
+			if err != nil {
+				return Variable{}, err
+			}
+
+			return booleanVariable(filepath.IsAbs(paths[0])), nil
+		},
+		"separator": func(call *CallContext, args []Variable) (Variable, error) {
+			if err := expectNoArguments(call, args); err != nil {
+				return Variable{}, err
+			}
+
+			return stringVariable(string(filepath.Separator)), nil
+		},
+	}
+
+	for name, function := range map[string]func(string) string{
+		"dir":  cleanDir,
+		"base": filepath.Base,
+		// the extension with the dot such as .gal, empty if there is none
+		"ext": func(path string) string {
+			return filepath.Ext(filepath.Clean(path))
+		},
+		// removes duplicate and trailing separators and resolves . and ..
+		"clean": filepath.Clean,
+	} {
+		functions[name] = func(call *CallContext, args []Variable) (Variable, error) {
+			paths, err := pathArguments(call, args, 1)
+			if err != nil {
+				return Variable{}, err
+			}
+
+			return stringVariable(function(paths[0])), nil
+		}
+	}
+
+	return &NativeModule{
+		Name:      "path",
+		Functions: functions,
+	}
+}
+
+// the directory of the last part of the path, filepath.Dir("a/b/") would be a/b
+func cleanDir(path string) string {
+	return filepath.Dir(filepath.Clean(path))
+}
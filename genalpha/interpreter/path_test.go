@@ -0,0 +1,53 @@
+package interpreter
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	s := stringVariable
+	p := filepath.FromSlash
+	parts := arrayVariable([]Variable{s("src"), s("lib"), s("c.gal")})
+	mixed := arrayVariable([]Variable{s("src"), numberVariable(1)})
+	testModule(t, PathModule(), []moduleTest{
+		{function: "join", args: []Variable{s("a"), s("b"), s("c.txt")}, want: p("a/b/c.txt")},
+		{function: "join", args: []Variable{s("a/"), s(""), s("./b//")}, want: p("a/b")},
+		{function: "join", args: []Variable{s("a/b"), s("../../..")}, want: ".."},
+		{function: "join", args: []Variable{s(""), s("")}, want: ""},
+		{function: "join", args: []Variable{parts}, want: p("src/lib/c.gal")},
+		{function: "join", args: []Variable{mixed}, err: ErrorKindArgument, want: "path.join expects an array of strings, got 1 at index 1"},
+		{function: "join", args: []Variable{s("a"), noneVariable()}, err: ErrorKindArgument, want: "path.join expects a string as argument 2, got nuthin"},
+		{function: "dir", args: []Variable{s("a/b/")}, want: "a"},
+		{function: "dir", args: []Variable{s("file.gal")}, want: "."},
+		{function: "base", args: []Variable{s("a/b/")}, want: "b"},
+		{function: "ext", args: []Variable{s("a/archive.tar.gz")}, want: ".gz"},
+		{function: "ext", args: []Variable{s("a.d/README")}, want: ""},
+		{function: "ext", args: []Variable{s("main.gal/")}, want: ".gal"},
+		{function: "clean", args: []Variable{s("a//b/./../c/")}, want: p("a/c")},
+		{function: "split", args: []Variable{s("a/b/c.gal")}, want: "2"},
+		{function: "relative", args: []Variable{s("a/b"), s("a/c/d")}, want: p("../c/d")},
+		{function: "relative", args: []Variable{s("a"), s(p("/abs"))}, err: ErrorKindArgument},
+		{function: "is_abs", args: []Variable{s("a/b")}, want: "nay"},
+		{function: "separator", want: string(filepath.Separator)},
+		{function: "base", args: []Variable{s("a"), s("b")}, err: ErrorKindArgument, want: "path.base expects exactly 1 argument"},
+	})
+}
+
+// path.join(base, path.relative(base, target)) is target
+func TestPathRelativeJoin(t *testing.T) {
+	interpreterState := NewInterpreter([]string{})
+	module := PathModule()
+	for _, test := range [][2]string{{"a/b", "a/c/d"}, {"a", "a"}, {".", "x/y"}, {"a/b/c", "a"}} {
+		base, target := stringVariable(filepath.FromSlash(test[0])), stringVariable(filepath.FromSlash(test[1]))
+		relative, err := callModule(interpreterState, module, "relative", base, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		joined, err := callModule(interpreterState, module, "join", base, relative)
+		if err != nil || joined.Value != target.Value {
+			t.Errorf("joining %s and %s gave %s, %v, want %s", base.Value, relative.Value, joined.Value, err, target.Value)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package interpreter
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// the profile.proto format read by go tool pprof, see
+// https://github.com/google/pprof/blob/main/proto/profile.proto
+// it is small enough to encode by hand instead of depending on protobuf
+
+type protoBuffer struct {
+	data []byte
+}
+
+func (buffer *protoBuffer) varint(value uint64) {
+	for value >= 0x80 {
+		buffer.data = append(buffer.data, byte(value)|0x80)
+		value >>= 7
+	}
+	buffer.data = append(buffer.data, byte(value))
+}
+
+func (buffer *protoBuffer) key(field int, wireType int) {
+	buffer.varint(uint64(field<<3 | wireType))
+}
+
+func (buffer *protoBuffer) int(field int, value int64) {
+	if value == 0 {
+		return
+	}
+	buffer.key(field, 0)
+	buffer.varint(uint64(value))
+}
+
+func (buffer *protoBuffer) bytes(field int, value []byte) {
+	buffer.key(field, 2)
+	buffer.varint(uint64(len(value)))
+	buffer.data = append(buffer.data, value...)
+}
+
+func (buffer *protoBuffer) packed(field int, values []int64) {
+	packed := protoBuffer{}
+	for _, value := range values {
+		packed.varint(uint64(value))
+	}
+	buffer.bytes(field, packed.data)
+}
+
+func (buffer *protoBuffer) message(field int, encode func(message *protoBuffer)) {
+	message := protoBuffer{}
+	encode(&message)
+	buffer.bytes(field, message.data)
+}
+
+type pprofWriter struct {
+	profile   protoBuffer
+	strings   map[string]int64
+	functions map[CallFrame]int64 // by function and file, the line is 0
+	locations map[CallFrame]int64
+}
+
+func (writer *pprofWriter) string(value string) int64 {
+	if id, ok := writer.strings[value]; ok {
+		return id
+	}
+
+	id := int64(len(writer.strings))
+	writer.strings[value] = id
+	writer.profile.bytes(6, []byte(value))
+	return id
+}
+
+func (writer *pprofWriter) location(frame CallFrame, declarations map[CallFrame]int) int64 {
+	if id, ok := writer.locations[frame]; ok {
+		return id
+	}
+
+	function := CallFrame{Function: frame.Function, Filename: frame.Filename}
+	functionID, ok := writer.functions[function]
+	if !ok {
+		functionID = int64(len(writer.functions) + 1)
+		writer.functions[function] = functionID
+		name := writer.string(frame.Function)
+		filename := writer.string(frame.Filename)
+		writer.profile.message(5, func(message *protoBuffer) {
+			message.int(1, functionID)
+			message.int(2, name)
+			message.int(3, name)
+			message.int(4, filename)
+			message.int(5, int64(declarations[function]))
+		})
+	}
+
+	// before its first statement a function is on the line it is declared on
+	line := frame.Line
+	if line == 0 {
+		line = declarations[function]
+	}
+
+	id := int64(len(writer.locations) + 1)
+	writer.locations[frame] = id
+	writer.profile.message(4, func(message *protoBuffer) {
+		message.int(1, id)
+		message.message(4, func(message *protoBuffer) {
+			message.int(1, functionID)
+			message.int(2, int64(line))
+		})
+	})
+	return id
+}
+
+// WritePprof writes the profile gzipped in the format of go tool pprof, every
+// sample is a call stack with the statements run and the time spent on it
+func (profiler *Profiler) WritePprof(out io.Writer) error {
+	writer := &pprofWriter{
+		strings:   map[string]int64{},
+		functions: map[CallFrame]int64{},
+		locations: map[CallFrame]int64{},
+	}
+	writer.string("")
+
+	valueType := func(field int, kind string, unit string) {
+		kindID, unitID := writer.string(kind), writer.string(unit)
+		writer.profile.message(field, func(message *protoBuffer) {
+			message.int(1, kindID)
+			message.int(2, unitID)
+		})
+	}
+	valueType(1, "statements", "count")
+	valueType(1, "time", "nanoseconds")
+
+	topLevel := CallFrame{Function: "<top level>"}
+	profiler.walk(func(stack []*profileNode) {
+		time := profiler.root.time
+		statements := profiler.root.statements
+		frames := []CallFrame{topLevel}
+		if len(stack) != 0 {
+			time = stack[len(stack)-1].time
+			statements = stack[len(stack)-1].statements
+			frames = frames[:0]
+			for _, node := range stack {
+				frames = append(frames, node.frame)
+			}
+		}
+		if time == 0 && statements == 0 {
+			return
+		}
+
+		// the innermost frame comes first
+		locations := []int64{}
+		for i := len(frames) - 1; i >= 0; i-- {
+			locations = append(locations, writer.location(frames[i], profiler.declarations))
+		}
+		writer.profile.message(2, func(message *protoBuffer) {
+			message.packed(1, locations)
+			message.packed(2, []int64{int64(statements), int64(time)})
+		})
+	})
+
+	writer.profile.int(9, profiler.start.UnixNano())
+	writer.profile.int(10, int64(profiler.end.Sub(profiler.start)))
+	valueType(11, "time", "nanoseconds")
+	writer.profile.int(12, 1)
+
+	compressed := gzip.NewWriter(out)
+	_, err := compressed.Write(writer.profile.data)
+	if err != nil {
+		return err
+	}
+
+	return compressed.Close()
+}
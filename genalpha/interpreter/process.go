@@ -0,0 +1,194 @@
+package interpreter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// the options of process.run and process.shell
+type processOptions struct {
+	dir     string
+	env     []string
+	stdin   *string
+	timeout time.Duration
+	stream  bool
+	check   bool
+}
+
+// ProcessModule returns the process module for running other programs.
+// A program that exits with a status other than 0 does not throw unless the
+// check option is set, the status is returned
+func ProcessModule() *NativeModule {
+	return &NativeModule{
+		Name: "process",
+		Functions: map[string]NativeFunction{
+			// process.run(command, options) runs the program with the arguments
+			// in the command array and returns its stdout, stderr and code
+			"run": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 2); err != nil {
+					return Variable{}, err
+				}
+				if args[0].Type == genalphatypes.ASTNodeTypeString {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, "process.run expects an array of the program and its arguments, use process.shell to run a command line")
+				}
+
+				command := []string{}
+				for i, member := range arrayMembers(args[0]) {
+					if member.Type != genalphatypes.ASTNodeTypeString && member.Type != genalphatypes.ASTNodeTypeNumber {
+						return Variable{}, NewRuntimeError(ErrorKindArgument, "process.run expects strings in the command, got "+formatValue(member)+" at index "+strconv.Itoa(i))
+					}
+					command = append(command, member.Value)
+				}
+				if len(command) == 0 {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, "process.run expects a command that is not empty")
+				}
+
+				options, err := parseProcessOptions(call, args[1:])
+				if err != nil {
+					return Variable{}, err
+				}
+
+				return runProcess(call, command, options)
+			},
+			// process.shell(line, options) runs the command line with sh, or
+			// cmd on windows, so it can use pipes, globs and variables
+			"shell": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 2); err != nil {
+					return Variable{}, err
+				}
+				line, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				options, err := parseProcessOptions(call, args[1:])
+				if err != nil {
+					return Variable{}, err
+				}
+
+				if runtime.GOOS == "windows" {
+					return runProcess(call, []string{"cmd", "/C", line}, options)
+				}
+				return runProcess(call, []string{"sh", "-c", line}, options)
+			},
+		},
+	}
+}
+
+func parseProcessOptions(call *CallContext, args []Variable) (processOptions, error) {
+	options := processOptions{}
+	if len(args) == 0 {
+		return options, nil
+	}
+
+	for key, value := range args[0].Indecies {
+		if value == nil {
+			continue
+		}
+
+		switch key {
+		case "dir":
+			options.dir = value.Value
+		case "env":
+			names := []string{}
+			for name := range value.Indecies {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			// added to the environment of gal, later ones win
+			options.env = os.Environ()
+			for _, name := range names {
+				options.env = append(options.env, name+"="+value.Indecies[name].Value)
+			}
+		case "stdin":
+			stdin := value.Value
+			options.stdin = &stdin
+		case "timeout":
+			seconds, err := strconv.ParseFloat(value.Value, 64)
+			if value.Type != genalphatypes.ASTNodeTypeNumber || err != nil || seconds <= 0 {
+				return options, NewRuntimeError(ErrorKindArgument, call.Name+" expects a timeout in seconds greater than 0, got "+formatValue(*value))
+			}
+			options.timeout = time.Duration(seconds * float64(time.Second))
+		case "stream":
+			options.stream = value.Value == string(genalphatypes.KeywordTrue)
+		case "check":
+			options.check = value.Value == string(genalphatypes.KeywordTrue)
+		default:
+			return options, NewRuntimeError(ErrorKindArgument, call.Name+": unknown option "+strconv.Quote(key)+", expected dir, env, stdin, timeout, stream or check")
+		}
+	}
+
+	return options, nil
+}
+
+func runProcess(call *CallContext, command []string, options processOptions) (Variable, error) {
+	ctx := context.Background()
+	if options.timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = options.dir
+	cmd.Env = options.env
+	// output of programs the killed one started does not keep it waiting
+	cmd.WaitDelay = time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if options.stream {
+		// what gal printed before shows up before the output of the program
+		call.Interpreter.Stdout.Flush()
+		cmd.Stdout = io.MultiWriter(&stdout, call.Interpreter.Stdout)
+		cmd.Stderr = io.MultiWriter(&stderr, os.Stderr)
+		cmd.Stdin = os.Stdin
+	}
+	if options.stdin != nil {
+		cmd.Stdin = strings.NewReader(*options.stdin)
+	}
+
+	err := cmd.Run()
+	if options.stream {
+		call.Interpreter.Stdout.Flush()
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return Variable{}, NewRuntimeError(ErrorKindProcess, call.Name+": "+command[0]+" timed out after "+options.timeout.String())
+	}
+
+	code := 0
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		code = exitError.ExitCode()
+	} else if err != nil {
+		return Variable{}, NewRuntimeError(ErrorKindProcess, call.Name+": "+err.Error())
+	}
+
+	if options.check && code != 0 {
+		message := call.Name + ": " + command[0] + " exited with status " + strconv.Itoa(code)
+		if output := strings.TrimSpace(stderr.String()); output != "" && !options.stream {
+			message += ": " + output
+		}
+		return Variable{}, NewRuntimeError(ErrorKindProcess, message)
+	}
+
+	return objectVariable(map[string]Variable{
+		"stdout": stringVariable(stdout.String()),
+		"stderr": stringVariable(stderr.String()),
+		"code":   numberVariable(float64(code)),
+	}), nil
+}
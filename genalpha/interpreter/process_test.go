@@ -0,0 +1,120 @@
+package interpreter
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runShell(t *testing.T, line string, options map[string]Variable) (map[string]string, error) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are written for sh")
+	}
+
+	args := []Variable{stringVariable(line)}
+	if options != nil {
+		args = append(args, objectVariable(options))
+	}
+	result, err := callModule(NewInterpreter([]string{}), ProcessModule(), "shell", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for key, value := range result.Indecies {
+		values[key] = value.Value
+	}
+	return values, nil
+}
+
+func TestShell(t *testing.T) {
+	dir := t.TempDir()
+	for _, test := range []struct {
+		line    string
+		options map[string]Variable
+		want    map[string]string
+	}{
+		{
+			line: "echo out; echo err >&2; exit 3",
+			want: map[string]string{"stdout": "out\n", "stderr": "err\n", "code": "3"},
+		},
+		{
+			line:    "cat; pwd",
+			options: map[string]Variable{"stdin": stringVariable("in\n"), "dir": stringVariable(dir)},
+			want:    map[string]string{"stdout": "in\n" + dir + "\n", "stderr": "", "code": "0"},
+		},
+		{
+			line:    `echo "$GAL_ONE $GAL_TWO"`,
+			options: map[string]Variable{"env": objectVariable(map[string]Variable{"GAL_ONE": stringVariable("1"), "GAL_TWO": numberVariable(2)})},
+			want:    map[string]string{"stdout": "1 2\n", "stderr": "", "code": "0"},
+		},
+		{
+			line:    "true",
+			options: map[string]Variable{"check": booleanVariable(true), "timeout": numberVariable(10)},
+			want:    map[string]string{"stdout": "", "stderr": "", "code": "0"},
+		},
+	} {
+		got, err := runShell(t, test.line, test.options)
+		if err != nil {
+			t.Errorf("%s: %v", test.line, err)
+			continue
+		}
+		for key, want := range test.want {
+			if key == "stdout" && test.options["dir"].Value != "" {
+				// the temporary directory can be behind a symlink
+				resolved, _ := filepath.EvalSymlinks(dir)
+				want = strings.Replace(want, dir, resolved, 1)
+			}
+			if got[key] != want {
+				t.Errorf("%s: %s is %q, want %q", test.line, key, got[key], want)
+			}
+		}
+	}
+}
+
+// a program that exits with a status other than 0 only throws with check
+func TestShellCheck(t *testing.T) {
+	_, err := runShell(t, "echo failed >&2; exit 2", map[string]Variable{"check": booleanVariable(true)})
+	runtimeError, ok := err.(*RuntimeError)
+	if !ok || runtimeError.Kind != ErrorKindProcess || runtimeError.Message != "process.shell: sh exited with status 2: failed" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestShellTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := runShell(t, "sleep 10", map[string]Variable{"timeout": numberVariable(0.2)})
+	runtimeError, ok := err.(*RuntimeError)
+	if !ok || runtimeError.Kind != ErrorKindProcess || runtimeError.Message != "process.shell: sh timed out after 200ms" {
+		t.Errorf("got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the program was stopped after %s", elapsed)
+	}
+}
+
+func TestProcessRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are written for sh")
+	}
+
+	s := stringVariable
+
+	// the arguments are passed as they are, numbers too
+	result, err := callModule(NewInterpreter([]string{}), ProcessModule(), "run", arrayVariable([]Variable{s("sh"), s("-c"), s("echo $0 $1"), s("a b"), numberVariable(1)}))
+	if err != nil || result.Indecies["stdout"].Value != "a b 1\n" {
+		t.Errorf("got %+v, %v", result, err)
+	}
+
+	testModule(t, ProcessModule(), []moduleTest{
+		{function: "run", args: []Variable{s("echo hi")}, err: ErrorKindArgument, want: "process.run expects an array of the program and its arguments, use process.shell to run a command line"},
+		{function: "run", args: []Variable{arrayVariable(nil)}, err: ErrorKindArgument, want: "process.run expects a command that is not empty"},
+		{function: "run", args: []Variable{arrayVariable([]Variable{s("echo"), noneVariable()})}, err: ErrorKindArgument, want: "process.run expects strings in the command, got nuthin at index 1"},
+		{function: "run", args: []Variable{arrayVariable([]Variable{s("gal-program-that-does-not-exist")})}, err: ErrorKindProcess},
+		{function: "shell", args: []Variable{s("true"), objectVariable(map[string]Variable{"timeout": numberVariable(0)})}, err: ErrorKindArgument, want: "process.shell expects a timeout in seconds greater than 0, got 0"},
+		{function: "shell", args: []Variable{s("true"), objectVariable(map[string]Variable{"cwd": s(".")})}, err: ErrorKindArgument, want: `process.shell: unknown option "cwd", expected dir, env, stdin, timeout, stream or check`},
+	})
+}
@@ -0,0 +1,225 @@
+package interpreter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Profiler measures how much time a gal program spends on every line of every
+// function and how often functions are called. Set it on the interpreter before
+// the program runs, the time between two statements is added to the call stack
+// of the first one.
+type Profiler struct {
+	start time.Time
+	last  time.Time
+	end   time.Time
+
+	root         *profileNode
+	calls        map[CallFrame]int // by function and file, the line is 0
+	declarations map[CallFrame]int // line the function is declared on
+}
+
+// a node of the call tree, the path from the root is a call stack including
+// the line every function was running
+type profileNode struct {
+	frame      CallFrame
+	children   map[CallFrame]*profileNode
+	time       time.Duration
+	statements int
+}
+
+func NewProfiler() *Profiler {
+	now := time.Now()
+	return &Profiler{
+		start: now,
+		last:  now,
+		root: &profileNode{
+			children: map[CallFrame]*profileNode{},
+		},
+		calls:        map[CallFrame]int{},
+		declarations: map[CallFrame]int{},
+	}
+}
+
+func (node *profileNode) child(frame CallFrame) *profileNode {
+	child := node.children[frame]
+	if child == nil {
+		child = &profileNode{
+			frame:    frame,
+			children: map[CallFrame]*profileNode{},
+		}
+		node.children[frame] = child
+	}
+
+	return child
+}
+
+func (profiler *Profiler) node(interpreterState *InterpreterState) *profileNode {
+	node := profiler.root
+	for _, frame := range interpreterState.CallStack {
+		node = node.child(frame)
+	}
+
+	return node
+}
+
+// adds the time since the last tick to the current call stack, called before
+// the call stack or the line of its innermost frame changes
+func (profiler *Profiler) tick(interpreterState *InterpreterState) {
+	now := time.Now()
+	profiler.node(interpreterState).time += now.Sub(profiler.last)
+	profiler.last = now
+}
+
+// counts a statement on the line the innermost frame is on now
+func (profiler *Profiler) statement(interpreterState *InterpreterState) {
+	profiler.node(interpreterState).statements++
+}
+
+func (profiler *Profiler) call(interpreterState *InterpreterState, function Function) {
+	profiler.tick(interpreterState)
+	frame := CallFrame{Function: function.Name, Filename: function.Filename}
+	profiler.calls[frame]++
+	profiler.declarations[frame] = function.Line
+}
+
+// Stop ends the measurement, the time until now goes to what was running last
+func (profiler *Profiler) Stop(interpreterState *InterpreterState) {
+	profiler.tick(interpreterState)
+	profiler.end = profiler.last
+}
+
+func (profiler *Profiler) walk(visit func(stack []*profileNode)) {
+	var walk func(node *profileNode, stack []*profileNode)
+	walk = func(node *profileNode, stack []*profileNode) {
+		visit(stack)
+		for _, child := range node.children {
+			walk(child, append(stack, child))
+		}
+	}
+
+	walk(profiler.root, []*profileNode{})
+}
+
+type profileEntry struct {
+	frame      CallFrame
+	own        time.Duration
+	total      time.Duration
+	statements int
+	calls      int
+}
+
+// the time of every function, a function that is on the stack more than once
+// because of recursion only counts once towards its total
+func (profiler *Profiler) functions() []profileEntry {
+	entries := map[CallFrame]*profileEntry{}
+	entry := func(frame CallFrame) *profileEntry {
+		frame.Line = 0
+		if entries[frame] == nil {
+			entries[frame] = &profileEntry{
+				frame: frame,
+				calls: profiler.calls[frame],
+			}
+		}
+		return entries[frame]
+	}
+
+	profiler.walk(func(stack []*profileNode) {
+		if len(stack) == 0 {
+			return
+		}
+
+		node := stack[len(stack)-1]
+		entry(node.frame).own += node.time
+		entry(node.frame).statements += node.statements
+
+		counted := map[*profileEntry]bool{}
+		for _, caller := range stack {
+			callerEntry := entry(caller.frame)
+			if !counted[callerEntry] {
+				callerEntry.total += node.time
+				counted[callerEntry] = true
+			}
+		}
+	})
+
+	return sortEntries(entries)
+}
+
+// the time spent on every line itself, without the functions it calls, the
+// time a function is called but has not started its first statement is left out
+func (profiler *Profiler) lines() []profileEntry {
+	entries := map[CallFrame]*profileEntry{}
+	profiler.walk(func(stack []*profileNode) {
+		if len(stack) == 0 || stack[len(stack)-1].frame.Line == 0 {
+			return
+		}
+
+		node := stack[len(stack)-1]
+		if entries[node.frame] == nil {
+			entries[node.frame] = &profileEntry{
+				frame: node.frame,
+			}
+		}
+		entries[node.frame].own += node.time
+		entries[node.frame].statements += node.statements
+	})
+
+	return sortEntries(entries)
+}
+
+func sortEntries(entries map[CallFrame]*profileEntry) []profileEntry {
+	sorted := []profileEntry{}
+	for _, entry := range entries {
+		sorted = append(sorted, *entry)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].own != sorted[j].own {
+			return sorted[i].own > sorted[j].own
+		}
+		if sorted[i].total != sorted[j].total {
+			return sorted[i].total > sorted[j].total
+		}
+
+		return sorted[i].frame.Function+sorted[i].frame.Filename < sorted[j].frame.Function+sorted[j].frame.Filename
+	})
+
+	return sorted
+}
+
+// WriteReport writes the top functions and lines by the time spent in them
+func (profiler *Profiler) WriteReport(out io.Writer, top int) {
+	total := profiler.end.Sub(profiler.start)
+	percent := func(duration time.Duration) string {
+		if total == 0 {
+			return "0.0%"
+		}
+		return fmt.Sprintf("%.1f%%", 100*float64(duration)/float64(total))
+	}
+	seconds := func(duration time.Duration) string {
+		return fmt.Sprintf("%.3fs", duration.Seconds())
+	}
+
+	fmt.Fprintf(out, "profile: %s total\n\n", seconds(total))
+
+	fmt.Fprintf(out, "%9s %6s %9s %6s %9s  %s\n", "own", "", "total", "", "calls", "function")
+	for i, entry := range profiler.functions() {
+		if i == top {
+			break
+		}
+
+		fmt.Fprintf(out, "%9s %6s %9s %6s %9d  %s (%s:%d)\n", seconds(entry.own), percent(entry.own), seconds(entry.total), percent(entry.total), entry.calls, entry.frame.Function, entry.frame.Filename, profiler.declarations[entry.frame])
+	}
+
+	fmt.Fprintf(out, "\n%9s %6s %11s  %s\n", "own", "", "statements", "line")
+	for i, entry := range profiler.lines() {
+		if i == top {
+			break
+		}
+
+		fmt.Fprintf(out, "%9s %6s %11d  %s:%d in %s\n", seconds(entry.own), percent(entry.own), entry.statements, entry.frame.Filename, entry.frame.Line, entry.frame.Function)
+	}
+}
@@ -0,0 +1,123 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Seed makes the rand module return the same numbers on every run with the
+// same seed, gal run -seed and rand.seed call it
+func (interpreterState *InterpreterState) Seed(seed uint64) {
+	interpreterState.Random = rand.New(rand.NewPCG(seed, seed))
+}
+
+// RandModule returns the rand module, random numbers, choices and uuids from
+// the generator of the interpreter. They are not suitable for secrets
+func RandModule() *NativeModule {
+	return &NativeModule{
+		Name: "rand",
+		Functions: map[string]NativeFunction{
+			"seed": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				seed, err := numberArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				call.Interpreter.Seed(uint64(int64(seed)))
+				return noneVariable(), nil
+			},
+			// rand.int(min, max) returns a whole number from min to max, both included
+			"int": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 2, 2); err != nil {
+					return Variable{}, err
+				}
+				low, err := intArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+				high, err := intArgument(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+				if low > high {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, fmt.Sprintf("rand.int min %d is greater than max %d", low, high))
+				}
+
+				return numberVariable(float64(low + call.Interpreter.Random.IntN(high-low+1))), nil
+			},
+			// rand.float(min, max) returns a number from min up to max, from 0 up
+			// to 1 without arguments
+			"float": func(call *CallContext, args []Variable) (Variable, error) {
+				if expectNoArguments(call, args) == nil {
+					return numberVariable(call.Interpreter.Random.Float64()), nil
+				}
+				if len(args) != 2 {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, "rand.float expects 0 or 2 arguments")
+				}
+				low, err := numberArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+				high, err := numberArgument(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+				if low > high {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, "rand.float min "+args[0].Value+" is greater than max "+args[1].Value)
+				}
+
+				return numberVariable(low + call.Interpreter.Random.Float64()*(high-low)), nil
+			},
+			"bool": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectNoArguments(call, args); err != nil {
+					return Variable{}, err
+				}
+
+				return booleanVariable(call.Interpreter.Random.IntN(2) == 0), nil
+			},
+			"choice": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+
+				members := arrayMembers(args[0])
+				if len(members) == 0 {
+					return Variable{}, NewRuntimeError(ErrorKindIndex, "rand.choice expects an array that is not empty")
+				}
+
+				return members[call.Interpreter.Random.IntN(len(members))], nil
+			},
+			// returns a shuffled copy of the array
+			"shuffle": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+
+				members := arrayMembers(args[0])
+				call.Interpreter.Random.Shuffle(len(members), func(i, j int) {
+					members[i], members[j] = members[j], members[i]
+				})
+
+				return arrayVariable(members), nil
+			},
+			// a random version 4 uuid such as 0b5c8a3e-7d2f-4c1a-9e6b-2f8d4a7c1e90
+			"uuid": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectNoArguments(call, args); err != nil {
+					return Variable{}, err
+				}
+
+				uuid := make([]byte, 16)
+				for i := range uuid {
+					uuid[i] = byte(call.Interpreter.Random.UintN(256))
+				}
+				uuid[6] = uuid[6]&0x0f | 0x40 // version 4
+				uuid[8] = uuid[8]&0x3f | 0x80 // variant 10
+
+				return stringVariable(fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])), nil
+			},
+		},
+	}
+}
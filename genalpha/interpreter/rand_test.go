@@ -0,0 +1,113 @@
+package interpreter
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// draws one of everything the rand module returns
+func draw(t *testing.T, interpreterState *InterpreterState) string {
+	t.Helper()
+	module := RandModule()
+	values := []string{}
+	for _, test := range []moduleTest{
+		{function: "int", args: []Variable{numberVariable(1), numberVariable(1000000)}},
+		{function: "float"},
+		{function: "float", args: []Variable{numberVariable(-5), numberVariable(5)}},
+		{function: "bool"},
+		{function: "choice", args: []Variable{arrayVariable([]Variable{stringVariable("a"), stringVariable("b"), stringVariable("c")})}},
+		{function: "uuid"},
+	} {
+		result, err := callModule(interpreterState, module, test.function, test.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, result.Value)
+	}
+
+	shuffled, err := callModule(interpreterState, module, "shuffle", arrayVariable([]Variable{numberVariable(1), numberVariable(2), numberVariable(3), numberVariable(4)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range arrayMembers(shuffled) {
+		values = append(values, member.Value)
+	}
+
+	return strings.Join(values, " ")
+}
+
+// gal run -seed and rand.seed make a program draw the same numbers every run
+func TestRandSeed(t *testing.T) {
+	seeded := NewInterpreter([]string{})
+	seeded.Seed(42)
+	first := draw(t, seeded)
+
+	again := NewInterpreter([]string{})
+	again.Seed(42)
+	if second := draw(t, again); second != first {
+		t.Errorf("the same seed drew\n%s\n%s", first, second)
+	}
+
+	fromGal := NewInterpreter([]string{})
+	if _, err := callModule(fromGal, RandModule(), "seed", numberVariable(42)); err != nil {
+		t.Fatal(err)
+	}
+	if third := draw(t, fromGal); third != first {
+		t.Errorf("rand.seed(42) drew\n%s\nwhile -seed 42 drew\n%s", third, first)
+	}
+
+	other := NewInterpreter([]string{})
+	other.Seed(43)
+	if draw(t, other) == first {
+		t.Error("another seed drew the same values")
+	}
+}
+
+func TestRandRanges(t *testing.T) {
+	interpreterState := NewInterpreter([]string{})
+	module := RandModule()
+	uuid := regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$")
+
+	seen := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		number, err := callModule(interpreterState, module, "int", numberVariable(-1), numberVariable(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[number.Value] = true
+
+		float, err := callModule(interpreterState, module, "float", numberVariable(2), numberVariable(2.5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value := parseNumber(float.Value); value < 2 || value >= 2.5 {
+			t.Fatalf("rand.float(2, 2.5) returned %s", float.Value)
+		}
+
+		id, err := callModule(interpreterState, module, "uuid", noneVariable())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !uuid.MatchString(id.Value) {
+			t.Fatalf("%s is not a version 4 uuid", id.Value)
+		}
+	}
+
+	if len(seen) != 3 || !seen["-1"] || !seen["0"] || !seen["1"] {
+		t.Errorf("rand.int(-1, 1) returned %v", seen)
+	}
+}
+
+func TestRandArguments(t *testing.T) {
+	n := numberVariable
+	testModule(t, RandModule(), []moduleTest{
+		{function: "int", args: []Variable{n(3), n(1)}, err: ErrorKindArgument, want: "rand.int min 3 is greater than max 1"},
+		{function: "int", args: []Variable{n(1), n(1.5)}, err: ErrorKindArgument, want: "rand.int expects a whole number as argument 2, got 1.5"},
+		{function: "int", args: []Variable{n(7), n(7)}, want: "7"},
+		{function: "float", args: []Variable{n(1)}, err: ErrorKindArgument, want: "rand.float expects 0 or 2 arguments"},
+		{function: "float", args: []Variable{n(2), n(1)}, err: ErrorKindArgument, want: "rand.float min 2 is greater than max 1"},
+		{function: "choice", args: []Variable{arrayVariable(nil)}, err: ErrorKindIndex, want: "rand.choice expects an array that is not empty"},
+		{function: "bool", args: []Variable{n(1)}, err: ErrorKindArgument, want: "rand.bool expects no arguments"},
+	})
+}
@@ -0,0 +1,277 @@
+package interpreter
+
+import (
+	"regexp"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// ReModule returns the re module for regular expressions with the syntax of
+// Go, see https://pkg.go.dev/regexp/syntax. Patterns are given as strings and
+// compiled once, an invalid one throws an error of kind regex
+func ReModule() *NativeModule {
+	return &NativeModule{
+		Name: "re",
+		Functions: map[string]NativeFunction{
+			// re.compile(pattern) checks the pattern and returns it, so mistakes
+			// are found before it is used
+			"compile": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				if _, err := regexpArgument(call, args, 0); err != nil {
+					return Variable{}, err
+				}
+
+				return args[0], nil
+			},
+			// re.match(pattern, s) is yay if the pattern matches anywhere in s,
+			// use ^ and $ to match all of it
+			"match": func(call *CallContext, args []Variable) (Variable, error) {
+				re, s, err := regexpArguments(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				return booleanVariable(re.MatchString(s)), nil
+			},
+			// the first match, nuthin if there is none
+			"find": func(call *CallContext, args []Variable) (Variable, error) {
+				re, s, err := regexpArguments(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				match := re.FindStringIndex(s)
+				if match == nil {
+					return noneVariable(), nil
+				}
+
+				return stringVariable(s[match[0]:match[1]]), nil
+			},
+			"find_all": func(call *CallContext, args []Variable) (Variable, error) {
+				re, s, err := regexpArguments(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				matches := []Variable{}
+				for _, match := range re.FindAllString(s, -1) {
+					matches = append(matches, stringVariable(match))
+				}
+
+				return arrayVariable(matches), nil
+			},
+			// re.groups(pattern, s) returns the first match and its capture
+			// groups in an array, the match at 0 and group n at n. Groups that
+			// did not take part in the match are nuthin
+			"groups": func(call *CallContext, args []Variable) (Variable, error) {
+				re, s, err := regexpArguments(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				match := re.FindStringSubmatchIndex(s)
+				if match == nil {
+					return noneVariable(), nil
+				}
+
+				return groupsVariable(s, match), nil
+			},
+			"groups_all": func(call *CallContext, args []Variable) (Variable, error) {
+				re, s, err := regexpArguments(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				matches := []Variable{}
+				for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+					matches = append(matches, groupsVariable(s, match))
+				}
+
+				return arrayVariable(matches), nil
+			},
+			// re.named(pattern, s) returns the named groups such as (?P<level>\w+)
+			// of the first match by their name
+			"named": func(call *CallContext, args []Variable) (Variable, error) {
+				re, s, err := regexpArguments(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				match := re.FindStringSubmatchIndex(s)
+				if match == nil {
+					return noneVariable(), nil
+				}
+
+				return namedVariable(re, s, match), nil
+			},
+			"named_all": func(call *CallContext, args []Variable) (Variable, error) {
+				re, s, err := regexpArguments(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				matches := []Variable{}
+				for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+					matches = append(matches, namedVariable(re, s, match))
+				}
+
+				return arrayVariable(matches), nil
+			},
+			// re.replace(pattern, s, template) replaces every match with the
+			// template, $1 or ${name} in it are the groups and $$ is a $
+			"replace": func(call *CallContext, args []Variable) (Variable, error) {
+				re, s, err := regexpArguments(call, args, 3)
+				if err != nil {
+					return Variable{}, err
+				}
+				template, err := stringArgument(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				return stringVariable(re.ReplaceAllString(s, template)), nil
+			},
+			// re.replace_func(pattern, s, "function") replaces every match with
+			// what the gal function returns, it is called with the match and the
+			// array re.groups would return
+			"replace_func": func(call *CallContext, args []Variable) (Variable, error) {
+				re, s, err := regexpArguments(call, args, 3)
+				if err != nil {
+					return Variable{}, err
+				}
+				name, err := stringArgument(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+				if call.Interpreter.Functions[name].Name == "" {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, "re.replace_func expects the name of a gal function, "+name+" not found")
+				}
+
+				var result strings.Builder
+				last := 0
+				for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+					replacement, err := call.Call(name, stringVariable(s[match[0]:match[1]]), groupsVariable(s, match))
+					if err != nil {
+						return Variable{}, err
+					}
+					if replacement.Type != genalphatypes.ASTNodeTypeString && replacement.Type != genalphatypes.ASTNodeTypeNumber {
+						return Variable{}, NewRuntimeError(ErrorKindType, "re.replace_func expects "+name+" to return a string, got "+formatValue(replacement))
+					}
+
+					result.WriteString(s[last:match[0]])
+					result.WriteString(replacement.Value)
+					last = match[1]
+				}
+				result.WriteString(s[last:])
+
+				return stringVariable(result.String()), nil
+			},
+			// re.split(pattern, s) returns the parts of s between the matches
+			"split": func(call *CallContext, args []Variable) (Variable, error) {
+				re, s, err := regexpArguments(call, args, 2)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				parts := []Variable{}
+				for _, part := range re.Split(s, -1) {
+					parts = append(parts, stringVariable(part))
+				}
+
+				return arrayVariable(parts), nil
+			},
+			// re.escape(s) returns a pattern that matches s literally
+			"escape": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				s, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				return stringVariable(regexp.QuoteMeta(s)), nil
+			},
+		},
+	}
+}
+
+// the number of compiled patterns that are kept, patterns can come from input
+// so there may be any number of them
+const maxRegexps = 256
+
+// the compiled pattern at i, patterns are compiled again only once more than
+// maxRegexps were used
+func regexpArgument(call *CallContext, args []Variable, i int) (*regexp.Regexp, error) {
+	pattern, err := stringArgument(call, args, i)
+	if err != nil {
+		return nil, err
+	}
+
+	interpreterState := call.Interpreter
+	if re := interpreterState.regexps[pattern]; re != nil {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, NewRuntimeError(ErrorKindRegex, call.Name+": "+strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+	}
+
+	if interpreterState.regexps == nil || len(interpreterState.regexps) == maxRegexps {
+		interpreterState.regexps = map[string]*regexp.Regexp{}
+	}
+	interpreterState.regexps[pattern] = re
+
+	return re, nil
+}
+
+// checks that there are n arguments, the pattern and the string to search
+func regexpArguments(call *CallContext, args []Variable, n int) (*regexp.Regexp, string, error) {
+	if err := expectArguments(call, args, n, n); err != nil {
+		return nil, "", err
+	}
+	re, err := regexpArgument(call, args, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	s, err := stringArgument(call, args, 1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return re, s, nil
+}
+
+// the match and its groups from the indecies FindStringSubmatchIndex returns
+func groupsVariable(s string, match []int) Variable {
+	groups := make([]Variable, len(match)/2)
+	for i := range groups {
+		groups[i] = submatch(s, match, i)
+	}
+
+	return arrayVariable(groups)
+}
+
+func namedVariable(re *regexp.Regexp, s string, match []int) Variable {
+	named := map[string]Variable{}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			named[name] = submatch(s, match, i)
+		}
+	}
+
+	return objectVariable(named)
+}
+
+// group i of the match, nuthin if it did not take part
+func submatch(s string, match []int, i int) Variable {
+	if match[2*i] < 0 {
+		return noneVariable()
+	}
+
+	return stringVariable(s[match[2*i]:match[2*i+1]])
+}
@@ -0,0 +1,28 @@
+package interpreter
+
+import (
+	"strconv"
+	"testing"
+)
+
+// patterns can come from input, the compiled ones kept must not grow with them
+func TestRegexpsAreBounded(t *testing.T) {
+	interpreterState := NewInterpreter([]string{})
+	module := ReModule()
+	call := &CallContext{Interpreter: interpreterState, Module: module, Name: "re.match"}
+
+	for i := 0; i < 3*maxRegexps; i++ {
+		args := []Variable{stringVariable("^" + strconv.Itoa(i) + "$"), stringVariable(strconv.Itoa(i))}
+		matched, err := module.Functions["match"](call, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matched.Value != "yay" {
+			t.Fatalf("pattern %d did not match", i)
+		}
+
+		if len(interpreterState.regexps) > maxRegexps {
+			t.Fatalf("%d patterns are kept after %d were used", len(interpreterState.regexps), i+1)
+		}
+	}
+}
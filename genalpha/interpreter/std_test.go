@@ -0,0 +1,34 @@
+package interpreter
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// calls the native function of the module the way gal does
+func callModule(interpreterState *InterpreterState, module *NativeModule, name string, args ...Variable) (Variable, error) {
+	call := &CallContext{Interpreter: interpreterState, Module: module, Name: module.Name + "." + name}
+	return module.Functions[name](call, args)
+}
+
+// the indecies of an array are a map, the parts have to be joined by index and
+// not in the order of the map or sorted as strings, where 10 comes before 2
+func TestJoinInIndexOrder(t *testing.T) {
+	members := []Variable{}
+	want := []string{}
+	for i := 0; i < 12; i++ {
+		members = append(members, stringVariable("part"+strconv.Itoa(i)))
+		want = append(want, "part"+strconv.Itoa(i))
+	}
+
+	for try := 0; try < 10; try++ {
+		joined, err := callModule(NewInterpreter([]string{}), StdModule(), "join", arrayVariable(members), stringVariable(","))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if joined.Value != strings.Join(want, ",") {
+			t.Fatalf("got %s", joined.Value)
+		}
+	}
+}
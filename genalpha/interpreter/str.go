@@ -0,0 +1,355 @@
+package interpreter
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// StrModule returns the str module with the string functions, positions and
+// lengths count characters instead of bytes unlike std.len and std.slice
+func StrModule() *NativeModule {
+	return &NativeModule{
+		Name: "str",
+		Functions: map[string]NativeFunction{
+			"upper": func(call *CallContext, args []Variable) (Variable, error) {
+				return mapString(call, args, strings.ToUpper)
+			},
+			"lower": func(call *CallContext, args []Variable) (Variable, error) {
+				return mapString(call, args, strings.ToLower)
+			},
+			"reverse": func(call *CallContext, args []Variable) (Variable, error) {
+				return mapString(call, args, func(s string) string {
+					runes := []rune(s)
+					for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+						runes[i], runes[j] = runes[j], runes[i]
+					}
+					return string(runes)
+				})
+			},
+			// str.trim(s, chars) removes the characters in chars from both ends,
+			// whitespace if chars is not given
+			"trim": func(call *CallContext, args []Variable) (Variable, error) {
+				return trimString(call, args, strings.TrimSpace, strings.Trim)
+			},
+			"trim_left": func(call *CallContext, args []Variable) (Variable, error) {
+				return trimString(call, args, func(s string) string {
+					return strings.TrimLeft(s, " \t\n\r\v\f")
+				}, strings.TrimLeft)
+			},
+			"trim_right": func(call *CallContext, args []Variable) (Variable, error) {
+				return trimString(call, args, func(s string) string {
+					return strings.TrimRight(s, " \t\n\r\v\f")
+				}, strings.TrimRight)
+			},
+			"trim_prefix": func(call *CallContext, args []Variable) (Variable, error) {
+				return compareStrings(call, args, func(s string, prefix string) Variable {
+					return stringVariable(strings.TrimPrefix(s, prefix))
+				})
+			},
+			"trim_suffix": func(call *CallContext, args []Variable) (Variable, error) {
+				return compareStrings(call, args, func(s string, suffix string) Variable {
+					return stringVariable(strings.TrimSuffix(s, suffix))
+				})
+			},
+			"contains": func(call *CallContext, args []Variable) (Variable, error) {
+				return compareStrings(call, args, func(s string, substring string) Variable {
+					return booleanVariable(strings.Contains(s, substring))
+				})
+			},
+			"starts_with": func(call *CallContext, args []Variable) (Variable, error) {
+				return compareStrings(call, args, func(s string, prefix string) Variable {
+					return booleanVariable(strings.HasPrefix(s, prefix))
+				})
+			},
+			"ends_with": func(call *CallContext, args []Variable) (Variable, error) {
+				return compareStrings(call, args, func(s string, suffix string) Variable {
+					return booleanVariable(strings.HasSuffix(s, suffix))
+				})
+			},
+			"count": func(call *CallContext, args []Variable) (Variable, error) {
+				return compareStrings(call, args, func(s string, substring string) Variable {
+					if substring == "" {
+						return numberVariable(float64(utf8.RuneCountInString(s) + 1))
+					}
+					return numberVariable(float64(strings.Count(s, substring)))
+				})
+			},
+			// the character position of the first occurrence, -1 if there is none
+			"index_of": func(call *CallContext, args []Variable) (Variable, error) {
+				return compareStrings(call, args, func(s string, substring string) Variable {
+					return numberVariable(float64(runeIndex(s, strings.Index(s, substring))))
+				})
+			},
+			"last_index_of": func(call *CallContext, args []Variable) (Variable, error) {
+				return compareStrings(call, args, func(s string, substring string) Variable {
+					return numberVariable(float64(runeIndex(s, strings.LastIndex(s, substring))))
+				})
+			},
+			// str.replace(s, old, new, n) replaces the first n occurrences, all of
+			// them if n is not given
+			"replace": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 3, 4); err != nil {
+					return Variable{}, err
+				}
+
+				strs := make([]string, 3)
+				for i := range strs {
+					str, err := stringArgument(call, args, i)
+					if err != nil {
+						return Variable{}, err
+					}
+					strs[i] = str
+				}
+
+				n := -1
+				if len(args) == 4 {
+					count, err := intArgument(call, args, 3)
+					if err != nil {
+						return Variable{}, err
+					}
+					n = count
+				}
+
+				return stringVariable(strings.Replace(strs[0], strs[1], strs[2], n)), nil
+			},
+			// str.pad_left(s, width, pad) puts pad in front of s until it is width
+			// characters long, pad is a space if it is not given
+			"pad_left": func(call *CallContext, args []Variable) (Variable, error) {
+				return padString(call, args, true)
+			},
+			"pad_right": func(call *CallContext, args []Variable) (Variable, error) {
+				return padString(call, args, false)
+			},
+			"len": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				s, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				return numberVariable(float64(utf8.RuneCountInString(s))), nil
+			},
+			// str.slice(s, start, end) returns the characters from start up to
+			// end, or up to the end of s if end is not given
+			"slice": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 2, 3); err != nil {
+					return Variable{}, err
+				}
+				s, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+				start, err := intArgument(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				runes := []rune(s)
+				end := len(runes)
+				if len(args) == 3 {
+					end, err = intArgument(call, args, 2)
+					if err != nil {
+						return Variable{}, err
+					}
+				}
+
+				if start < 0 || end > len(runes) || start > end {
+					return Variable{}, NewRuntimeError(ErrorKindIndex, "str.slice range "+strconv.Itoa(start)+":"+strconv.Itoa(end)+" out of range for string of length "+strconv.Itoa(len(runes)))
+				}
+
+				return stringVariable(string(runes[start:end])), nil
+			},
+			"chars": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				s, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				chars := []Variable{}
+				for _, char := range s {
+					chars = append(chars, stringVariable(string(char)))
+				}
+
+				return arrayVariable(chars), nil
+			},
+			// str.to_number(s, base) parses a number, whole numbers can be in any
+			// base from 2 to 36. Throws an error of kind "type" if s is not a number
+			"to_number": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 2); err != nil {
+					return Variable{}, err
+				}
+				s, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				base := 10
+				if len(args) == 2 {
+					base, err = intArgument(call, args, 1)
+					if err != nil {
+						return Variable{}, err
+					}
+					if base < 2 || base > 36 {
+						return Variable{}, NewRuntimeError(ErrorKindArgument, "str.to_number base must be between 2 and 36, got "+strconv.Itoa(base))
+					}
+				}
+
+				number, ok := parseString(s, base)
+				if !ok {
+					return Variable{}, NewRuntimeError(ErrorKindType, "str.to_number: "+strconv.Quote(s)+" is not a number")
+				}
+
+				return numberVariable(number), nil
+			},
+			"is_number": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				s, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				_, ok := parseString(s, 10)
+				return booleanVariable(ok), nil
+			},
+			// str.from_number(n, decimals) formats n with the number of decimals,
+			// as short as possible if decimals is not given
+			"from_number": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 2); err != nil {
+					return Variable{}, err
+				}
+				number, err := numberArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				if len(args) == 1 {
+					return stringVariable(strconv.FormatFloat(number, 'f', -1, 64)), nil
+				}
+
+				decimals, err := intArgument(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+				if decimals < 0 {
+					return Variable{}, NewRuntimeError(ErrorKindArgument, "str.from_number expects a number of decimals of 0 or more, got "+strconv.Itoa(decimals))
+				}
+
+				return stringVariable(strconv.FormatFloat(number, 'f', decimals, 64)), nil
+			},
+		},
+	}
+}
+
+// calls f with the only argument, which must be a string
+func mapString(call *CallContext, args []Variable, f func(string) string) (Variable, error) {
+	if err := expectArguments(call, args, 1, 1); err != nil {
+		return Variable{}, err
+	}
+	s, err := stringArgument(call, args, 0)
+	if err != nil {
+		return Variable{}, err
+	}
+
+	return stringVariable(f(s)), nil
+}
+
+// calls f with the two arguments, which must be strings
+func compareStrings(call *CallContext, args []Variable, f func(string, string) Variable) (Variable, error) {
+	if err := expectArguments(call, args, 2, 2); err != nil {
+		return Variable{}, err
+	}
+	a, err := stringArgument(call, args, 0)
+	if err != nil {
+		return Variable{}, err
+	}
+	b, err := stringArgument(call, args, 1)
+	if err != nil {
+		return Variable{}, err
+	}
+
+	return f(a, b), nil
+}
+
+func trimString(call *CallContext, args []Variable, space func(string) string, cutset func(string, string) string) (Variable, error) {
+	if err := expectArguments(call, args, 1, 2); err != nil {
+		return Variable{}, err
+	}
+	if len(args) == 1 {
+		return mapString(call, args, space)
+	}
+
+	return compareStrings(call, args, func(s string, chars string) Variable {
+		return stringVariable(cutset(s, chars))
+	})
+}
+
+func padString(call *CallContext, args []Variable, left bool) (Variable, error) {
+	if err := expectArguments(call, args, 2, 3); err != nil {
+		return Variable{}, err
+	}
+	s, err := stringArgument(call, args, 0)
+	if err != nil {
+		return Variable{}, err
+	}
+	width, err := intArgument(call, args, 1)
+	if err != nil {
+		return Variable{}, err
+	}
+	pad := " "
+	if len(args) == 3 {
+		pad, err = stringArgument(call, args, 2)
+		if err != nil {
+			return Variable{}, err
+		}
+		if pad == "" {
+			return Variable{}, NewRuntimeError(ErrorKindArgument, call.Name+" expects a string to pad with that is not empty")
+		}
+	}
+
+	missing := width - utf8.RuneCountInString(s)
+	if missing <= 0 {
+		return stringVariable(s), nil
+	}
+
+	// a pad of several characters is cut off where the width is reached
+	padding := []rune(strings.Repeat(pad, missing))[:missing]
+	if left {
+		return stringVariable(string(padding) + s), nil
+	}
+
+	return stringVariable(s + string(padding)), nil
+}
+
+// the character position of a byte position, -1 stays -1
+func runeIndex(s string, index int) int {
+	if index < 0 {
+		return index
+	}
+
+	return utf8.RuneCountInString(s[:index])
+}
+
+// parses a number surrounded by optional whitespace, nan and infinity are not numbers
+func parseString(s string, base int) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if base != 10 {
+		number, err := strconv.ParseInt(s, base, 64)
+		return float64(number), err == nil
+	}
+
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+		return 0, false
+	}
+
+	return number, true
+}
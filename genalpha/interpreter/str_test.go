@@ -0,0 +1,82 @@
+package interpreter
+
+import (
+	"testing"
+)
+
+type moduleTest struct {
+	function string
+	args     []Variable
+	want     string // the value returned, or the message of the error if err is set and want is not empty
+	err      string // the kind of the error expected
+}
+
+// calls the functions of the module with the arguments of every test
+func testModule(t *testing.T, module *NativeModule, tests []moduleTest) {
+	t.Helper()
+	for _, test := range tests {
+		result, err := callModule(NewInterpreter([]string{}), module, test.function, test.args...)
+		if test.err == "" {
+			if err != nil || result.Value != test.want {
+				t.Errorf("%s.%s%v = %q, %v, want %q", module.Name, test.function, test.args, result.Value, err, test.want)
+			}
+			continue
+		}
+
+		runtimeError, ok := err.(*RuntimeError)
+		if !ok || runtimeError.Kind != test.err || test.want != "" && runtimeError.Message != test.want {
+			t.Errorf("%s.%s%v = %v, want an error of kind %s: %s", module.Name, test.function, test.args, err, test.err, test.want)
+		}
+	}
+}
+
+func TestStr(t *testing.T) {
+	s := stringVariable
+	n := numberVariable
+	testModule(t, StrModule(), []moduleTest{
+		{function: "upper", args: []Variable{s("ñandú")}, want: "ÑANDÚ"},
+		{function: "reverse", args: []Variable{s("ñandú 🐦")}, want: "🐦 údnañ"},
+		{function: "len", args: []Variable{s("ñandú")}, want: "5"},
+		{function: "len", args: []Variable{s("")}, want: "0"},
+		{function: "slice", args: []Variable{s("ñandú"), n(1), n(4)}, want: "and"},
+		{function: "slice", args: []Variable{s("ñandú"), n(3)}, want: "dú"},
+		{function: "slice", args: []Variable{s("ñandú"), n(2), n(6)}, err: ErrorKindIndex, want: "str.slice range 2:6 out of range for string of length 5"},
+		{function: "slice", args: []Variable{s("ñandú"), n(1.5)}, err: ErrorKindArgument, want: "str.slice expects a whole number as argument 2, got 1.5"},
+		{function: "chars", args: []Variable{s("ñú")}, want: "2"},
+		{function: "index_of", args: []Variable{s("ñandú ñandú"), s("dú")}, want: "3"},
+		{function: "last_index_of", args: []Variable{s("ñandú ñandú"), s("dú")}, want: "9"},
+		{function: "index_of", args: []Variable{s("ñandú"), s("x")}, want: "-1"},
+		{function: "count", args: []Variable{s("ñandú"), s("")}, want: "6"},
+		{function: "count", args: []Variable{s("banana"), s("an")}, want: "2"},
+		{function: "pad_left", args: []Variable{s("ñ"), n(4), s("áé")}, want: "áéáñ"},
+		{function: "pad_right", args: []Variable{s("ñandú"), n(3)}, want: "ñandú"},
+		{function: "pad_left", args: []Variable{s("7"), n(3), s("")}, err: ErrorKindArgument, want: "str.pad_left expects a string to pad with that is not empty"},
+		{function: "trim", args: []Variable{s("  ñ \n")}, want: "ñ"},
+		{function: "trim", args: []Variable{s("ññañ"), s("ñ")}, want: "a"},
+		{function: "trim_left", args: []Variable{s("--a--"), s("-")}, want: "a--"},
+		{function: "trim", args: []Variable{s("a"), s("b"), s("c")}, err: ErrorKindArgument, want: "str.trim expects 1 or 2 arguments"},
+		{function: "replace", args: []Variable{s("aaa"), s("a"), s("ñ"), n(2)}, want: "ñña"},
+		{function: "contains", args: []Variable{s("ñandú"), n(1)}, err: ErrorKindArgument, want: "str.contains expects a string as argument 2, got 1"},
+		{function: "to_number", args: []Variable{s(" 1e2 ")}, want: "100"},
+		{function: "to_number", args: []Variable{s("ff"), n(16)}, want: "255"},
+		{function: "to_number", args: []Variable{s("ff"), n(37)}, err: ErrorKindArgument, want: "str.to_number base must be between 2 and 36, got 37"},
+		{function: "to_number", args: []Variable{s("NaN")}, err: ErrorKindType, want: `str.to_number: "NaN" is not a number`},
+		{function: "is_number", args: []Variable{s("Inf")}, want: "nay"},
+		{function: "from_number", args: []Variable{n(1e21)}, want: "1000000000000000000000"},
+		{function: "from_number", args: []Variable{n(2.345), n(1)}, want: "2.3"},
+		{function: "from_number", args: []Variable{n(1), n(-1)}, err: ErrorKindArgument, want: "str.from_number expects a number of decimals of 0 or more, got -1"},
+	})
+}
+
+// the members of str.chars are whole characters, not bytes
+func TestStrChars(t *testing.T) {
+	chars, err := callModule(NewInterpreter([]string{}), StrModule(), "chars", stringVariable("añ🐦"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	members := arrayMembers(chars)
+	if len(members) != 3 || members[0].Value != "a" || members[1].Value != "ñ" || members[2].Value != "🐦" {
+		t.Errorf("got %+v", members)
+	}
+}
@@ -0,0 +1,248 @@
+package interpreter
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // time zones work on systems without a zoneinfo database too
+)
+
+// the start of the monotonic clock of time.monotonic
+var monotonicStart = time.Now()
+
+// layouts that can be given by name instead of the reference time
+var timeLayouts = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123Z,
+	"date":     time.DateOnly,
+	"time":     time.TimeOnly,
+	"datetime": time.DateTime,
+	"kitchen":  time.Kitchen,
+}
+
+// TimeModule returns the time module. Timestamps are the number of seconds
+// since 1970-01-01 UTC and durations are seconds, so they can be added and
+// subtracted with + and -
+func TimeModule() *NativeModule {
+	return &NativeModule{
+		Name: "time",
+		Functions: map[string]NativeFunction{
+			"now": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectNoArguments(call, args); err != nil {
+					return Variable{}, err
+				}
+
+				return numberVariable(timestamp(time.Now())), nil
+			},
+			// seconds since an arbitrary start, not affected by changes of the
+			// system clock so the difference of two calls is the elapsed time
+			"monotonic": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectNoArguments(call, args); err != nil {
+					return Variable{}, err
+				}
+
+				return numberVariable(time.Since(monotonicStart).Seconds()), nil
+			},
+			"sleep": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				seconds, err := numberArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				// what was printed before sleeping shows up before it
+				call.Interpreter.Stdout.Flush()
+				time.Sleep(time.Duration(seconds * float64(time.Second)))
+				return noneVariable(), nil
+			},
+			// time.format(timestamp, layout, zone) formats the timestamp in the
+			// zone, the local one if it is not given, rfc3339 is the default layout
+			"format": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 3); err != nil {
+					return Variable{}, err
+				}
+				seconds, err := numberArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+				layout, location, err := layoutArguments(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				return stringVariable(fromTimestamp(seconds).In(location).Format(layout)), nil
+			},
+			// time.parse(s, layout, zone) returns the timestamp, the zone is used
+			// if s does not have an offset
+			"parse": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 3); err != nil {
+					return Variable{}, err
+				}
+				s, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+				layout, location, err := layoutArguments(call, args, 1)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				parsed, err := time.ParseInLocation(layout, s, location)
+				if err != nil {
+					return Variable{}, NewRuntimeError(ErrorKindTime, "time.parse: "+strings.TrimPrefix(err.Error(), "parsing time "))
+				}
+
+				return numberVariable(timestamp(parsed)), nil
+			},
+			// time.date(timestamp, zone) splits the timestamp into year, month,
+			// day, hour, minute, second, weekday (0 is sunday), yearday, zone and
+			// offset, the offset of the zone from UTC in seconds
+			"date": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 2); err != nil {
+					return Variable{}, err
+				}
+				seconds, err := numberArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+				location := time.Local
+				if len(args) == 2 {
+					location, err = zoneArgument(call, args, 1)
+					if err != nil {
+						return Variable{}, err
+					}
+				}
+
+				t := fromTimestamp(seconds).In(location)
+				zone, offset := t.Zone()
+				return objectVariable(map[string]Variable{
+					"year":    numberVariable(float64(t.Year())),
+					"month":   numberVariable(float64(t.Month())),
+					"day":     numberVariable(float64(t.Day())),
+					"hour":    numberVariable(float64(t.Hour())),
+					"minute":  numberVariable(float64(t.Minute())),
+					"second":  numberVariable(float64(t.Second())),
+					"weekday": numberVariable(float64(t.Weekday())),
+					"yearday": numberVariable(float64(t.YearDay())),
+					"zone":    stringVariable(zone),
+					"offset":  numberVariable(float64(offset)),
+				}), nil
+			},
+			// time.add_date(timestamp, years, months, days, zone) moves the
+			// timestamp by calendar years, months and days in the zone, a day
+			// is not always 86400 seconds when the clocks change
+			"add_date": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 4, 5); err != nil {
+					return Variable{}, err
+				}
+				seconds, err := numberArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				date := make([]int, 3)
+				for i := range date {
+					date[i], err = intArgument(call, args, i+1)
+					if err != nil {
+						return Variable{}, err
+					}
+				}
+
+				location := time.Local
+				if len(args) == 5 {
+					location, err = zoneArgument(call, args, 4)
+					if err != nil {
+						return Variable{}, err
+					}
+				}
+
+				t := fromTimestamp(seconds).In(location).AddDate(date[0], date[1], date[2])
+				return numberVariable(timestamp(t)), nil
+			},
+			// time.duration("1h30m") returns the seconds in a duration such as
+			// 300ms, 10s, 1.5h or 2h45m
+			"duration": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				s, err := stringArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				duration, err := time.ParseDuration(s)
+				if err != nil {
+					return Variable{}, NewRuntimeError(ErrorKindTime, "time.duration: invalid duration "+strconv.Quote(s))
+				}
+
+				return numberVariable(duration.Seconds()), nil
+			},
+			// time.format_duration(5400) is 1h30m0s
+			"format_duration": func(call *CallContext, args []Variable) (Variable, error) {
+				if err := expectArguments(call, args, 1, 1); err != nil {
+					return Variable{}, err
+				}
+				seconds, err := numberArgument(call, args, 0)
+				if err != nil {
+					return Variable{}, err
+				}
+
+				return stringVariable(time.Duration(seconds * float64(time.Second)).String()), nil
+			},
+		},
+	}
+}
+
+func timestamp(t time.Time) float64 {
+	return float64(t.Unix()) + float64(t.Nanosecond())/1e9
+}
+
+func fromTimestamp(seconds float64) time.Time {
+	whole := math.Floor(seconds)
+	return time.Unix(int64(whole), int64(math.Round((seconds-whole)*float64(time.Second))))
+}
+
+// the optional layout and zone arguments starting at i
+func layoutArguments(call *CallContext, args []Variable, i int) (string, *time.Location, error) {
+	layout := time.RFC3339
+	if len(args) > i {
+		name, err := stringArgument(call, args, i)
+		if err != nil {
+			return "", nil, err
+		}
+
+		layout = name
+		if named, ok := timeLayouts[name]; ok {
+			layout = named
+		}
+	}
+
+	location := time.Local
+	if len(args) > i+1 {
+		zone, err := zoneArgument(call, args, i+1)
+		if err != nil {
+			return "", nil, err
+		}
+		location = zone
+	}
+
+	return layout, location, nil
+}
+
+// a zone such as "UTC", "Local" or "Europe/Prague"
+func zoneArgument(call *CallContext, args []Variable, i int) (*time.Location, error) {
+	name, err := stringArgument(call, args, i)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, NewRuntimeError(ErrorKindArgument, call.Name+": unknown time zone "+strconv.Quote(name))
+	}
+
+	return location, nil
+}
@@ -0,0 +1,31 @@
+package interpreter
+
+import "testing"
+
+// timestamps of dates outside 1678 to 2262 do not fit in int64 nanoseconds
+func TestTimeOutsideNanosecondRange(t *testing.T) {
+	s := stringVariable
+	n := numberVariable
+	testModule(t, TimeModule(), []moduleTest{
+		{function: "parse", args: []Variable{s("1500-01-01T00:00:00Z")}, want: "-14831769600"},
+		{function: "parse", args: []Variable{s("2500-06-01T12:00:00.5Z")}, want: "16738315200.5"},
+		{function: "format", args: []Variable{n(-14831769600), s("rfc3339"), s("UTC")}, want: "1500-01-01T00:00:00Z"},
+		{function: "add_date", args: []Variable{n(16738315200), n(1), n(0), n(0), s("UTC")}, want: "16769851200"},
+	})
+}
+
+func TestTime(t *testing.T) {
+	s := stringVariable
+	n := numberVariable
+	testModule(t, TimeModule(), []moduleTest{
+		{function: "parse", args: []Variable{s("2024-02-29 12:30:00"), s("datetime"), s("UTC")}, want: "1709209800"},
+		{function: "parse", args: []Variable{s("2024-02-29T12:30:00.25+01:00")}, want: "1709206200.25"},
+		{function: "parse", args: []Variable{s("yesterday")}, err: ErrorKindTime},
+		{function: "format", args: []Variable{n(1709209800.5), s("2006-01-02 15:04:05.0"), s("UTC")}, want: "2024-02-29 12:30:00.5"},
+		{function: "add_date", args: []Variable{n(1709209800), n(0), n(0), n(1), s("UTC")}, want: "1709296200"},
+		{function: "duration", args: []Variable{s("1h30m")}, want: "5400"},
+		{function: "duration", args: []Variable{s("soon")}, err: ErrorKindTime, want: `time.duration: invalid duration "soon"`},
+		{function: "format_duration", args: []Variable{n(5400)}, want: "1h30m0s"},
+		{function: "now", args: []Variable{n(1)}, err: ErrorKindArgument},
+	})
+}
@@ -0,0 +1,144 @@
+package interpreter
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// Tracer writes what a gal program does as JSON lines, one object per
+// statement run, function call, return and assignment. Every object has the
+// event, the function and file it happened in and the depth of the call stack.
+type Tracer struct {
+	Functions *regexp.Regexp // only events in matching functions are written, nil writes all
+	Files     *regexp.Regexp // only events in matching files are written, nil writes all
+
+	out *bufio.Writer
+	err error
+}
+
+func NewTracer(out io.Writer) *Tracer {
+	return &Tracer{
+		out: bufio.NewWriter(out),
+	}
+}
+
+// Flush writes the buffered events and returns the first error writing them
+func (tracer *Tracer) Flush() error {
+	err := tracer.out.Flush()
+	if tracer.err == nil {
+		tracer.err = err
+	}
+
+	return tracer.err
+}
+
+func (tracer *Tracer) write(interpreterState *InterpreterState, event map[string]any) {
+	function, _ := event["function"].(string)
+	filename, _ := event["file"].(string)
+	if tracer.Functions != nil && !tracer.Functions.MatchString(function) ||
+		tracer.Files != nil && !tracer.Files.MatchString(filename) {
+		return
+	}
+
+	event["depth"] = len(interpreterState.CallStack)
+	line, err := json.Marshal(event)
+	if err == nil {
+		_, err = tracer.out.Write(append(line, '\n'))
+	}
+	if err != nil && tracer.err == nil {
+		tracer.err = err
+	}
+}
+
+// the event is in the innermost function, or on the top level of filename
+func traceEvent(interpreterState *InterpreterState, kind string, filename string) map[string]any {
+	event := map[string]any{
+		"event": kind,
+		"file":  currentFilename(interpreterState, filename),
+	}
+	if len(interpreterState.CallStack) != 0 {
+		frame := interpreterState.CallStack[len(interpreterState.CallStack)-1]
+		event["function"] = frame.Function
+		event["line"] = frame.Line
+	}
+
+	return event
+}
+
+func traceStatement(interpreterState *InterpreterState, filename string, line int, column int) {
+	event := traceEvent(interpreterState, "statement", filename)
+	event["line"] = line
+	event["column"] = column
+	interpreterState.Tracer.write(interpreterState, event)
+}
+
+// called after the frame of the function is pushed
+func traceCall(interpreterState *InterpreterState, function Function, args []Variable) {
+	values := map[string]any{}
+	for i, arg := range function.Args {
+		if i < len(args) {
+			values[arg.Value] = traceValue(args[i], 0)
+		}
+	}
+
+	event := traceEvent(interpreterState, "call", "")
+	event["line"] = function.Line
+	event["args"] = values
+	interpreterState.Tracer.write(interpreterState, event)
+}
+
+// called before the frame of the function is popped
+func traceReturn(interpreterState *InterpreterState, value Variable) {
+	event := traceEvent(interpreterState, "return", "")
+	event["value"] = traceValue(value, 0)
+	interpreterState.Tracer.write(interpreterState, event)
+}
+
+// index is nil unless a member of the variable is assigned
+func traceAssign(interpreterState *InterpreterState, name string, index *Variable, value Variable) {
+	event := traceEvent(interpreterState, "assign", "")
+	event["name"] = name
+	if index != nil {
+		event["index"] = index.Value
+	}
+	event["value"] = traceValue(value, 0)
+	interpreterState.Tracer.write(interpreterState, event)
+}
+
+// converts a value to json, nuthin is null and a value with indecies is an
+// object of them, indecies that refer back to the value are cut off
+func traceValue(value Variable, depth int) any {
+	if len(value.Indecies) != 0 {
+		if depth == 16 {
+			return "..."
+		}
+
+		object := map[string]any{}
+		for key, member := range value.Indecies {
+			if member != nil {
+				object[key] = traceValue(*member, depth+1)
+			}
+		}
+		return object
+	}
+
+	switch value.Type {
+	case genalphatypes.ASTNodeTypeNone:
+		return nil
+	case genalphatypes.ASTNodeTypeBoolean:
+		return value.Value == string(genalphatypes.KeywordTrue)
+	case genalphatypes.ASTNodeTypeNumber:
+		number, err := strconv.ParseFloat(value.Value, 64)
+		if err == nil && !math.IsInf(number, 0) && !math.IsNaN(number) {
+			return number
+		}
+	}
+
+	return value.Value
+}
@@ -0,0 +1,93 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"bobik.squidwock.com/root/gal/genalpha/interpreter"
+	"bobik.squidwock.com/root/gal/genalpha/lexer"
+	"bobik.squidwock.com/root/gal/genalpha/parser"
+)
+
+const tracedSource = `lowkey main{}
+    fax x = fire add(1, 2)
+    x = x + 1
+    fax n = fire first(args)
+end
+
+lowkey add{a b}
+    rizzult a + b
+end
+
+lowkey first{list}
+    fax item = [list 0]
+    rizzult item
+end
+`
+
+func runTraced(t *testing.T, setup func(tracer *interpreter.Tracer)) []string {
+	var out bytes.Buffer
+	ast := parser.Parse(lexer.Lex(tracedSource))
+	interpreterState := interpreter.NewInterpreter([]string{"one", "two"})
+	for _, module := range interpreter.DefaultModules() {
+		interpreterState.RegisterModule(module)
+	}
+	interpreterState.Tracer = interpreter.NewTracer(&out)
+	if setup != nil {
+		setup(interpreterState.Tracer)
+	}
+
+	interpreterState.Load(&ast, "main.gal")
+	if code := interpreterState.Run(); code != 0 {
+		t.Fatalf("exited with %d", code)
+	}
+	if err := interpreterState.Tracer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func TestTrace(t *testing.T) {
+	want := []string{
+		`{"args":{},"depth":1,"event":"call","file":"main.gal","function":"main","line":1}`,
+		`{"column":5,"depth":1,"event":"statement","file":"main.gal","function":"main","line":2}`,
+		`{"args":{"a":1,"b":2},"depth":2,"event":"call","file":"main.gal","function":"add","line":7}`,
+		`{"column":5,"depth":2,"event":"statement","file":"main.gal","function":"add","line":8}`,
+		`{"depth":2,"event":"return","file":"main.gal","function":"add","line":8,"value":3}`,
+		`{"depth":1,"event":"assign","file":"main.gal","function":"main","line":2,"name":"x","value":3}`,
+		`{"column":5,"depth":1,"event":"statement","file":"main.gal","function":"main","line":3}`,
+		`{"depth":1,"event":"assign","file":"main.gal","function":"main","line":3,"name":"x","value":4}`,
+		`{"column":5,"depth":1,"event":"statement","file":"main.gal","function":"main","line":4}`,
+		`{"args":{"list":{"0":"one","1":"two"}},"depth":2,"event":"call","file":"main.gal","function":"first","line":11}`,
+		`{"column":5,"depth":2,"event":"statement","file":"main.gal","function":"first","line":12}`,
+		`{"depth":2,"event":"assign","file":"main.gal","function":"first","line":12,"name":"item","value":"one"}`,
+		`{"column":5,"depth":2,"event":"statement","file":"main.gal","function":"first","line":13}`,
+		`{"depth":2,"event":"return","file":"main.gal","function":"first","line":13,"value":"one"}`,
+		`{"depth":1,"event":"assign","file":"main.gal","function":"main","line":4,"name":"n","value":"one"}`,
+		`{"depth":1,"event":"return","file":"main.gal","function":"main","line":4,"value":null}`,
+	}
+
+	got := runTraced(t, nil)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTraceFilters(t *testing.T) {
+	for _, line := range runTraced(t, func(tracer *interpreter.Tracer) {
+		tracer.Functions = regexp.MustCompile("^add$")
+	}) {
+		if !strings.Contains(line, `"function":"add"`) {
+			t.Errorf("traced outside of add: %s", line)
+		}
+	}
+
+	if got := runTraced(t, func(tracer *interpreter.Tracer) {
+		tracer.Files = regexp.MustCompile(`other\.gal$`)
+	}); len(got) != 1 || got[0] != "" {
+		t.Errorf("traced a file that does not match: %v", got)
+	}
+}
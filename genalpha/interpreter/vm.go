@@ -0,0 +1,584 @@
+package interpreter
+
+import (
+	"math"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+)
+
+// vm executes the bytecode produced by the compiler in bytecode.go, it has to
+// behave exactly like the ast walker in interpreter.go which is kept as the reference
+type vm struct {
+	interpreterState *InterpreterState
+	functions        []*compiledFunction
+	functionIndex    map[string]int
+	natives          []compiledNative
+	globals          map[string]*vmValue
+}
+
+func newVM(interpreterState *InterpreterState) *vm {
+	machine := &vm{
+		interpreterState: interpreterState,
+		functionIndex:    map[string]int{},
+		globals:          map[string]*vmValue{},
+	}
+
+	machine.declare()
+	return machine
+}
+
+// compiles the functions and takes over the globals the vm does not know yet,
+// they are declared by loading the program and by gyat and lowkey while running
+func (machine *vm) declare() {
+	interpreterState := machine.interpreterState
+	for name, variable := range interpreterState.GlobalScope.Variables {
+		if machine.globals[name] == nil {
+			value := fromVariable(*variable)
+			machine.globals[name] = &value
+		}
+	}
+
+	// indexes first so functions can call functions declared after them
+	declared := []string{}
+	for name, function := range interpreterState.Functions {
+		if _, ok := machine.functionIndex[name]; ok {
+			continue
+		}
+
+		machine.functionIndex[name] = len(machine.functions)
+		machine.functions = append(machine.functions, &compiledFunction{
+			function: function,
+		})
+		declared = append(declared, name)
+	}
+
+	for _, name := range declared {
+		machine.functions[machine.functionIndex[name]] = compileFunction(machine, interpreterState.Functions[name])
+	}
+}
+
+// RunBytecode compiles the loaded program to bytecode and runs its main function
+// on the virtual machine, the output and exit code are the same as with Run
+func (interpreterState *InterpreterState) RunBytecode() int {
+	if interpreterState.Functions["main"].Name == "" {
+		panic("No main function found, please declare a main function with the name 'main'")
+	}
+
+	return interpreterState.RunBytecodeFunction("main")
+}
+
+// RunBytecodeFunction is RunFunction on the virtual machine
+func (interpreterState *InterpreterState) RunBytecodeFunction(name string) (code int) {
+	machine := newVM(interpreterState)
+
+	index, ok := machine.functionIndex[name]
+	if !ok {
+		panic(NewRuntimeError(ErrorKindRuntime, "Function "+name+" not found"))
+	}
+
+	interpreterState.vm = machine
+	defer func() {
+		interpreterState.vm = nil
+	}()
+	defer interpreterState.recoverExit(&code)
+
+	// like main the function runs in the initial local scope which holds args
+	function := machine.functions[index]
+	locals, stack := newFrame(function)
+	for slot, variableName := range function.names {
+		variable := interpreterState.LocalScope.Variables[variableName]
+		if variable != nil {
+			locals[slot] = fromVariable(*variable)
+		}
+	}
+
+	pushFrame(interpreterState, function.function)
+	if interpreterState.Tracer != nil {
+		traceCall(interpreterState, function.function, []Variable{})
+	}
+	result, _ := machine.exec(function, locals, stack, 0, len(function.code))
+	if interpreterState.Tracer != nil {
+		traceReturn(interpreterState, result.variable())
+	}
+	popFrame(interpreterState)
+
+	return exitCode(interpreterState, result.variable())
+}
+
+// calls a gal function by name, used when native functions call back into gal
+func (machine *vm) callFunction(name string, args []Variable) Variable {
+	function := machine.functions[machine.functionIndex[name]]
+	if len(function.argSlots) > len(args) {
+		panic(NewRuntimeError(ErrorKindArgument, "Invalid number of arguments for function "+name))
+	}
+
+	values := make([]vmValue, len(args))
+	for i, arg := range args {
+		values[i] = fromVariable(arg)
+	}
+
+	return machine.call(function, values).variable()
+}
+
+// the local slots and the stack of a call, allocated together since every
+// call needs both
+func newFrame(function *compiledFunction) ([]vmValue, []vmValue) {
+	values := make([]vmValue, len(function.names)+function.maxStack)
+	locals := values[:len(function.names):len(function.names)]
+	for i := range locals {
+		locals[i].kind = kindUndefined
+	}
+
+	return locals, values[len(function.names):]
+}
+
+func (machine *vm) call(function *compiledFunction, args []vmValue) vmValue {
+	if machine.interpreterState.interrupted.Load() {
+		handleInterrupt(machine.interpreterState)
+	}
+
+	locals, stack := newFrame(function)
+	for i, slot := range function.argSlots {
+		locals[slot] = args[i]
+	}
+
+	pushFrame(machine.interpreterState, function.function)
+	if machine.interpreterState.Tracer != nil {
+		variables := []Variable{}
+		for _, arg := range args {
+			variables = append(variables, arg.variable())
+		}
+		traceCall(machine.interpreterState, function.function, variables)
+	}
+	result, _ := machine.exec(function, locals, stack, 0, len(function.code))
+	if machine.interpreterState.Tracer != nil {
+		traceReturn(machine.interpreterState, result.variable())
+	}
+	popFrame(machine.interpreterState)
+	return result
+}
+
+// returns the variable a local slot refers to, locals shadow globals
+func (machine *vm) lookup(function *compiledFunction, locals []vmValue, slot int) *vmValue {
+	if locals[slot].kind != kindUndefined {
+		return &locals[slot]
+	}
+
+	return machine.globals[function.names[slot]]
+}
+
+// runs the code from start to end, returned is set if the function returned
+// a value, the stack is empty at the start of every statement so it is shared
+// with the tryna blocks that are run from here
+func (machine *vm) exec(function *compiledFunction, locals []vmValue, stack []vmValue, start int, end int) (result vmValue, returned bool) {
+	code := function.code
+	sp := 0
+	pc := start
+
+	// the line is only needed for stack traces, so it is recorded when a
+	// panic unwinds through here instead of on every statement
+	frame := len(machine.interpreterState.CallStack) - 1
+	finished := false
+	defer func() {
+		if !finished {
+			setFrameLine(machine.interpreterState, frame, function.lines[pc])
+		}
+	}()
+
+	for ; pc < end; pc++ {
+		ins := code[pc]
+
+		switch ins.op {
+		case opConstant:
+			stack[sp] = function.constants[ins.a]
+			sp++
+		case opNone:
+			stack[sp] = noneValue
+			sp++
+		case opLoad:
+			variable := machine.lookup(function, locals, ins.a)
+			if variable == nil {
+				panic(NewRuntimeError(ErrorKindRuntime, "Variable "+function.names[ins.a]+" not found"))
+			}
+			stack[sp] = *variable
+			sp++
+		case opDeclare:
+			sp--
+			locals[ins.a] = stack[sp]
+			if machine.interpreterState.Tracer != nil {
+				traceAssign(machine.interpreterState, function.names[ins.a], nil, stack[sp].variable())
+			}
+		case opDeclareGlobal:
+			sp--
+			value := stack[sp]
+			machine.globals[function.strings[ins.a]] = &value
+			if machine.interpreterState.Tracer != nil {
+				traceAssign(machine.interpreterState, function.strings[ins.a], nil, value.variable())
+			}
+		case opAssign:
+			sp--
+			value := stack[sp]
+			variable := machine.lookup(function, locals, ins.a)
+			if variable == nil {
+				panic(NewRuntimeError(ErrorKindRuntime, "Variable "+function.names[ins.a]+" not found"))
+			}
+
+			originalIndecies := variable.indecies
+			if value.indecies != nil {
+				for key, val := range value.indecies {
+					if val != nil {
+						originalIndecies[key] = val
+					}
+				}
+			}
+
+			value.indecies = originalIndecies
+			*variable = value
+			if machine.interpreterState.Tracer != nil {
+				traceAssign(machine.interpreterState, function.names[ins.a], nil, value.variable())
+			}
+		case opMemberLoad:
+			sp--
+			index := stack[sp]
+			variable := machine.lookup(function, locals, ins.a)
+			if variable == nil {
+				panic(NewRuntimeError(ErrorKindRuntime, "Variable "+function.names[ins.a]+" not found"))
+			}
+
+			key := index.text()
+			member := variable.indecies[key]
+			if member == nil {
+				member = variable.indecies[formatNumber(index.number())]
+			}
+
+			if member == nil {
+				stack[sp] = noneValue
+				sp++
+			} else {
+				stack[sp] = fromVariable(*member)
+				sp++
+			}
+		case opMemberStore:
+			sp--
+			value := stack[sp]
+			sp--
+			index := stack[sp]
+			variable := machine.lookup(function, locals, ins.a)
+			if variable == nil {
+				continue
+			}
+
+			if machine.interpreterState.Tracer != nil {
+				member := index.variable()
+				traceAssign(machine.interpreterState, function.names[ins.a], &member, value.variable())
+			}
+
+			if value.kind == genalphatypes.ASTNodeTypeNone {
+				delete(variable.indecies, index.text())
+				continue
+			}
+
+			if variable.indecies == nil {
+				variable.indecies = map[string]*Variable{}
+			}
+
+			member := value.variable()
+			variable.indecies[index.text()] = &member
+		case opAdd:
+			sp -= 2
+			left, right := stack[sp], stack[sp+1]
+			if left.kind == genalphatypes.ASTNodeTypeNumber {
+				stack[sp] = numberValue(left.number() + right.number())
+				sp++
+				continue
+			}
+
+			stack[sp] = vmValue{
+				kind:   genalphatypes.ASTNodeTypeString,
+				str:    left.text() + right.text(),
+				hasStr: true,
+			}
+			sp++
+		case opSubtract, opMultiply, opPower, opDivide:
+			sp -= 2
+			left, right := stack[sp], stack[sp+1]
+			if left.kind != genalphatypes.ASTNodeTypeNumber {
+				panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation "+arithmeticOperators[ins.op]))
+			}
+
+			switch ins.op {
+			case opSubtract:
+				stack[sp] = numberValue(left.number() - right.number())
+			case opMultiply:
+				stack[sp] = numberValue(left.number() * right.number())
+			case opPower:
+				stack[sp] = numberValue(math.Pow(left.number(), right.number()))
+			case opDivide:
+				stack[sp] = numberValue(left.number() / right.number())
+			}
+			sp++
+		case opModulo:
+			sp -= 2
+			left, right := stack[sp], stack[sp+1]
+			if left.kind != genalphatypes.ASTNodeTypeNumber {
+				panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation %"))
+			}
+
+			// like the ast walker the remainder is of the whole numbers
+			stack[sp] = numberValue(float64(int(left.number()) % int(right.number())))
+			sp++
+		case opEqual:
+			sp -= 2
+			left, right := stack[sp], stack[sp+1]
+			stack[sp] = booleanValue(equalValues(left, right))
+			sp++
+		case opNotEqual:
+			sp -= 2
+			left, right := stack[sp], stack[sp+1]
+			stack[sp] = booleanValue(!equalValues(left, right))
+			sp++
+		case opStrictEqual:
+			sp -= 2
+			left, right := stack[sp], stack[sp+1]
+			stack[sp] = booleanValue(left.kind == right.kind && equalValues(left, right))
+			sp++
+		case opStrictNotEqual:
+			sp -= 2
+			left, right := stack[sp], stack[sp+1]
+			stack[sp] = booleanValue(left.kind != right.kind || !equalValues(left, right))
+			sp++
+		case opGreater, opLess, opGreaterEqual, opLessEqual:
+			sp -= 2
+			left, right := stack[sp], stack[sp+1]
+			if left.kind != genalphatypes.ASTNodeTypeNumber {
+				panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation "+comparisonOperators[ins.op]))
+			}
+
+			switch ins.op {
+			case opGreater:
+				stack[sp] = booleanValue(left.number() > right.number())
+			case opLess:
+				stack[sp] = booleanValue(left.number() < right.number())
+			case opGreaterEqual:
+				stack[sp] = booleanValue(left.number() >= right.number())
+			case opLessEqual:
+				stack[sp] = booleanValue(left.number() <= right.number())
+			}
+			sp++
+		case opAnd:
+			sp -= 2
+			left, right := stack[sp], stack[sp+1]
+			if left.kind != genalphatypes.ASTNodeTypeBoolean {
+				panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation &&"))
+			}
+
+			stack[sp] = booleanValue(left.str == string(genalphatypes.KeywordTrue) && right.text() == string(genalphatypes.KeywordTrue))
+			sp++
+		case opOr:
+			sp -= 2
+			left, right := stack[sp], stack[sp+1]
+			if left.kind != genalphatypes.ASTNodeTypeBoolean {
+				panic(NewRuntimeError(ErrorKindType, "Invalid operand type for binary operation ||"))
+			}
+
+			stack[sp] = booleanValue(left.str == string(genalphatypes.KeywordTrue) || right.text() == string(genalphatypes.KeywordTrue))
+			sp++
+		case opNot:
+			sp--
+			operand := stack[sp]
+			if operand.kind != genalphatypes.ASTNodeTypeBoolean {
+				panic(NewRuntimeError(ErrorKindType, "Invalid operand type for unary operation !"))
+			}
+
+			stack[sp] = booleanValue(operand.str == string(genalphatypes.KeywordFalse))
+			sp++
+		case opJump:
+			// loops jump back, so a loop that never calls anything still
+			// runs the handler of os.on_interrupt
+			if machine.interpreterState.interrupted.Load() {
+				handleInterrupt(machine.interpreterState)
+			}
+			pc = ins.a - 1
+		case opJumpUnless:
+			sp--
+			condition := stack[sp]
+			if condition.kind != genalphatypes.ASTNodeTypeBoolean {
+				if ins.b == 1 {
+					panic(NewRuntimeError(ErrorKindType, "Invalid condition type for while statement"))
+				}
+				panic(NewRuntimeError(ErrorKindType, "Invalid condition type for if statement, got: "+condition.text()))
+			}
+
+			// an if only runs its body on yay while a loop only stops on nay
+			if ins.b == 1 && condition.str == string(genalphatypes.KeywordFalse) ||
+				ins.b == 0 && condition.str != string(genalphatypes.KeywordTrue) {
+				pc = ins.a - 1
+			}
+		case opCall:
+			sp -= ins.b
+			stack[sp] = machine.call(machine.functions[ins.a], stack[sp:sp+ins.b])
+			sp++
+		case opCallNative:
+			native := machine.natives[ins.a]
+			args := make([]Variable, ins.b)
+			sp -= ins.b
+			for i, arg := range stack[sp : sp+ins.b] {
+				args[i] = arg.variable()
+			}
+
+			call := CallContext{
+				Interpreter: machine.interpreterState,
+				Module:      native.module,
+				Name:        native.name,
+			}
+
+			result, err := callNative(&call, native.function, args)
+			if err != nil {
+				panic(err)
+			}
+			stack[sp] = fromVariable(result)
+			sp++
+
+			// natives such as time.sleep are where programs wait
+			if machine.interpreterState.interrupted.Load() {
+				handleInterrupt(machine.interpreterState)
+			}
+		case opFindName:
+			name := function.strings[ins.a]
+			index, ok := machine.functionIndex[name]
+			if !ok {
+				panic(NewRuntimeError(ErrorKindRuntime, "Function "+name+" not found"))
+			}
+			if len(machine.functions[index].argSlots) > ins.b {
+				panic(NewRuntimeError(ErrorKindArgument, "Invalid number of arguments for function "+name))
+			}
+
+			stack[sp] = numberValue(float64(index))
+			sp++
+		case opSkipArgument:
+			callee := machine.functions[int(stack[sp-ins.b-1].num)]
+			if ins.b >= len(callee.argSlots) {
+				stack[sp] = noneValue
+				sp++
+				pc = ins.a - 1
+			}
+		case opCallName:
+			callee := machine.functions[int(stack[sp-ins.b-1].num)]
+			sp -= ins.b + 1
+			stack[sp] = machine.call(callee, stack[sp+1:sp+1+len(callee.argSlots)])
+			sp++
+		case opInterpret:
+			// function bodies have no filename in the ast walker either
+			node := function.nodes[ins.a]
+			if node.Type == genalphatypes.ASTNodeTypeImport {
+				interpretImport(machine.interpreterState, node, "")
+			} else {
+				interpretFunctionDeclaration(machine.interpreterState, node, "")
+			}
+			machine.declare()
+		case opReturnIfValue:
+			sp--
+			value := stack[sp]
+			if value.kind != genalphatypes.ASTNodeTypeNone {
+				finished = true
+				return value, true
+			}
+		case opTry:
+			block := function.tries[ins.a]
+			value, returned := machine.try(function, locals, stack, pc+1, block)
+			if returned {
+				finished = true
+				return value, true
+			}
+			pc = block.end - 1
+		case opThrow:
+			sp--
+			panic(errorFromVariable(stack[sp].variable()))
+		case opFail:
+			if function.strings[ins.b] != "" {
+				panic(NewRuntimeError(function.strings[ins.b], function.strings[ins.a]))
+			}
+			panic(function.strings[ins.a])
+		case opLine:
+			setLine(machine.interpreterState, ins.a)
+			if machine.interpreterState.Coverage != nil {
+				machine.interpreterState.Coverage.statement(function.function.Filename, ins.a, ins.b)
+			}
+			if machine.interpreterState.Tracer != nil {
+				traceStatement(machine.interpreterState, function.function.Filename, ins.a, ins.b)
+			}
+		case opBranch:
+			condition := stack[sp-1]
+			if condition.kind == genalphatypes.ASTNodeTypeBoolean {
+				machine.interpreterState.Coverage.branch(function.function.Filename, ins.a, ins.b, condition.str == string(genalphatypes.KeywordTrue))
+			}
+		}
+	}
+
+	finished = true
+	return noneValue, false
+}
+
+func setFrameLine(interpreterState *InterpreterState, frame int, line int) {
+	if frame >= 0 && frame < len(interpreterState.CallStack) && line != 0 {
+		interpreterState.CallStack[frame].Line = line
+	}
+}
+
+// same as interpretTry
+func (machine *vm) try(function *compiledFunction, locals []vmValue, stack []vmValue, start int, block tryRange) (vmValue, bool) {
+	result, returned := noneValue, false
+	err := protect(machine.interpreterState, func() {
+		result, returned = machine.exec(function, locals, stack, start, block.bodyEnd)
+	})
+
+	if err != nil && block.hasCatch {
+		if block.catchSlot != -1 {
+			locals[block.catchSlot] = fromVariable(errorVariable(err))
+		}
+
+		err = protect(machine.interpreterState, func() {
+			result, returned = machine.exec(function, locals, stack, block.bodyEnd, block.catchEnd)
+		})
+	}
+
+	value, finallyReturned := machine.exec(function, locals, stack, block.catchEnd, block.end)
+	if finallyReturned {
+		return value, true
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return result, returned
+}
+
+// the ast walker compares the string values, comparing two computed numbers
+// directly gives the same result without formatting them
+func equalValues(left vmValue, right vmValue) bool {
+	if !left.hasStr && !right.hasStr {
+		if math.IsNaN(left.num) || math.IsNaN(right.num) {
+			return math.IsNaN(left.num) && math.IsNaN(right.num)
+		}
+
+		return left.num == right.num && math.Signbit(left.num) == math.Signbit(right.num)
+	}
+
+	return left.text() == right.text()
+}
+
+var arithmeticOperators = map[opcode]string{
+	opSubtract: "-",
+	opMultiply: "*",
+	opPower:    "**",
+	opDivide:   "/",
+}
+
+// the ast walker reports >= and <= as <
+var comparisonOperators = map[opcode]string{
+	opGreater:      ">",
+	opLess:         "<",
+	opGreaterEqual: "<",
+	opLessEqual:    "<",
+}
@@ -1,6 +1,9 @@
 package lexer
 
 import (
+	"fmt"
+	"io"
+	"strconv"
 	"strings"
 
 	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
@@ -14,15 +17,48 @@ func Lex(contents string) []genalphatypes.Token {
 
 		tokens = append(tokens, lexLine(line, i)...)
 		tokens = append(tokens, genalphatypes.Token{
-			Type:  genalphatypes.TokenTypeNewline,
-			Value: "\n",
-			Line:  i,
+			Type:   genalphatypes.TokenTypeNewline,
+			Value:  "\n",
+			Line:   i,
+			Column: len(line),
 		})
 	}
 
 	return tokens
 }
 
+// JSONToken is a token as written by gal tokens -json, unlike in Token the line
+// and column count from 1
+type JSONToken struct {
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+func NewJSONTokens(tokens []genalphatypes.Token) []JSONToken {
+	jsonTokens := []JSONToken{}
+	for _, token := range tokens {
+		jsonTokens = append(jsonTokens, JSONToken{
+			Type:   token.Type.String(),
+			Value:  token.Value,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
+		})
+	}
+
+	return jsonTokens
+}
+
+// PrintTokens writes a token per line with its line and column counting from
+// 1, its type and its quoted value
+func PrintTokens(out io.Writer, tokens []genalphatypes.Token) {
+	for _, token := range tokens {
+		position := strconv.Itoa(token.Line+1) + ":" + strconv.Itoa(token.Column+1)
+		fmt.Fprintf(out, "%-8s %-12s %s\n", position, token.Type, strconv.Quote(token.Value))
+	}
+}
+
 // todo escaped characters in strings
 func lexLine(line string, lineNum int) []genalphatypes.Token {
 	var tokens []genalphatypes.Token
@@ -37,7 +73,7 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 	for {
 		if i >= len(line) {
 			if inString {
-				panic("Unclosed string at line " + string(lineNum))
+				panic("Unclosed string at line " + strconv.Itoa(lineNum+1))
 			}
 
 			break
@@ -63,9 +99,10 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 					finalString = strings.ReplaceAll(finalString, replacable, replaces[j])
 				}
 				tokens = append(tokens, genalphatypes.Token{
-					Type:  genalphatypes.TokenTypeString,
-					Value: finalString,
-					Line:  lineNum,
+					Type:   genalphatypes.TokenTypeString,
+					Value:  finalString,
+					Line:   lineNum,
+					Column: tokenStart,
 				})
 				inString = false
 			}
@@ -84,9 +121,10 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 
 		if char == '`' {
 			tokens = append(tokens, genalphatypes.Token{
-				Type:  genalphatypes.TokenTypeComment,
-				Value: line[i:],
-				Line:  lineNum,
+				Type:   genalphatypes.TokenTypeComment,
+				Value:  line[i:],
+				Line:   lineNum,
+				Column: i,
 			})
 			break
 		}
@@ -98,9 +136,10 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 			}
 
 			tokens = append(tokens, genalphatypes.Token{
-				Type:  genalphatypes.TokenTypeWhitespace,
-				Value: string(line[i]),
-				Line:  lineNum,
+				Type:   genalphatypes.TokenTypeWhitespace,
+				Value:  string(line[i]),
+				Line:   lineNum,
+				Column: i,
 			})
 
 			i++
@@ -109,9 +148,10 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 
 		if char == '[' || char == ']' || char == '(' || char == ')' || char == '{' || char == '}' || char == ',' {
 			tokens = append(tokens, genalphatypes.Token{
-				Type:  genalphatypes.TokenTypePunctuation,
-				Value: string(char),
-				Line:  lineNum,
+				Type:   genalphatypes.TokenTypePunctuation,
+				Value:  string(char),
+				Line:   lineNum,
+				Column: i,
 			})
 
 			i++
@@ -120,9 +160,10 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 
 		if char == ';' {
 			tokens = append(tokens, genalphatypes.Token{
-				Type:  genalphatypes.TokenTypeNewline,
-				Value: string(char),
-				Line:  lineNum,
+				Type:   genalphatypes.TokenTypeNewline,
+				Value:  string(char),
+				Line:   lineNum,
+				Column: i,
 			})
 
 			i++
@@ -143,9 +184,10 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 				}
 
 				tokens = append(tokens, genalphatypes.Token{
-					Type:  genalphatypes.TokenTypeNumber,
-					Value: line[i:j],
-					Line:  lineNum,
+					Type:   genalphatypes.TokenTypeNumber,
+					Value:  line[i:j],
+					Line:   lineNum,
+					Column: i,
 				})
 
 				added = true
@@ -155,9 +197,10 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 
 			if !added {
 				tokens = append(tokens, genalphatypes.Token{
-					Type:  genalphatypes.TokenTypeNumber,
-					Value: line[i:],
-					Line:  lineNum,
+					Type:   genalphatypes.TokenTypeNumber,
+					Value:  line[i:],
+					Line:   lineNum,
+					Column: i,
 				})
 
 				i += len(line[i:])
@@ -168,9 +211,10 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 
 		if char == '+' || char == '-' || char == '*' || char == '/' || char == '%' || char == '=' || char == '!' || char == '<' || char == '>' || char == '&' || char == '|' || char == '^' {
 			tokens = append(tokens, genalphatypes.Token{
-				Type:  genalphatypes.TokenTypeOperator,
-				Value: string(char),
-				Line:  lineNum,
+				Type:   genalphatypes.TokenTypeOperator,
+				Value:  string(char),
+				Line:   lineNum,
+				Column: i,
 			})
 
 			i++
@@ -180,9 +224,10 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 		keyword := fromIndexContainsAny(line, i, genalphatypes.Keywords)
 		if keyword != "" {
 			tokens = append(tokens, genalphatypes.Token{
-				Type:  genalphatypes.TokenTypeKeyword,
-				Value: keyword,
-				Line:  lineNum,
+				Type:   genalphatypes.TokenTypeKeyword,
+				Value:  keyword,
+				Line:   lineNum,
+				Column: i,
 			})
 
 			i += len(keyword)
@@ -203,9 +248,10 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 				}
 
 				tokens = append(tokens, genalphatypes.Token{
-					Type:  genalphatypes.TokenTypeIdentifier,
-					Value: line[i:j],
-					Line:  lineNum,
+					Type:   genalphatypes.TokenTypeIdentifier,
+					Value:  line[i:j],
+					Line:   lineNum,
+					Column: i,
 				})
 
 				added = true
@@ -215,9 +261,10 @@ func lexLine(line string, lineNum int) []genalphatypes.Token {
 
 			if !added {
 				tokens = append(tokens, genalphatypes.Token{
-					Type:  genalphatypes.TokenTypeIdentifier,
-					Value: line[i:],
-					Line:  lineNum,
+					Type:   genalphatypes.TokenTypeIdentifier,
+					Value:  line[i:],
+					Line:   lineNum,
+					Column: i,
 				})
 
 				i += len(line[i:])
@@ -245,5 +292,18 @@ func fromIndexContainsWord(line string, i int, word string) bool {
 		return false
 	}
 
-	return line[i:i+len(word)] == word
+	if line[i:i+len(word)] != word {
+		return false
+	}
+
+	// so identifiers such as ending or fireworks are not split into a keyword and the rest
+	if i+len(word) < len(line) && isIdentifierChar(line[i+len(word)]) {
+		return false
+	}
+
+	return true
+}
+
+func isIdentifierChar(char byte) bool {
+	return char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || char == '_' || char == '.'
 }
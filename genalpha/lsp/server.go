@@ -0,0 +1,518 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+	"bobik.squidwock.com/root/gal/genalpha/checker"
+	"bobik.squidwock.com/root/gal/genalpha/interpreter"
+	"bobik.squidwock.com/root/gal/genalpha/lexer"
+	"bobik.squidwock.com/root/gal/genalpha/parser"
+)
+
+// Server is a language server for gal files, it only knows about the files the
+// editor opened and the files they import
+type Server struct {
+	reader  *bufio.Reader
+	writer  io.Writer
+	modules []*interpreter.NativeModule
+
+	documents map[string]string // contents of the open documents by normalized path
+	shutdown  bool
+}
+
+func NewServer(in io.Reader, out io.Writer, modules []*interpreter.NativeModule) *Server {
+	return &Server{
+		reader:    bufio.NewReader(in),
+		writer:    out,
+		modules:   modules,
+		documents: map[string]string{},
+	}
+}
+
+// Serve handles messages until the client sends exit or closes the input, the
+// returned exit code is 0 if the client asked the server to shut down first. A
+// message that is not valid JSON is answered with a parse error
+func (server *Server) Serve() (int, error) {
+	for {
+		msg, err := readMessage(server.reader)
+		if err == io.EOF {
+			return 1, nil
+		}
+		if errors.Is(err, errInvalidJSON) {
+			// the id of the request is not known, so the response has none
+			id := json.RawMessage("null")
+			err = writeMessage(server.writer, &message{
+				ID:    &id,
+				Error: &responseError{Code: errorParse, Message: err.Error()},
+			})
+			if err != nil {
+				return 1, err
+			}
+			continue
+		}
+		if err != nil {
+			return 1, err
+		}
+
+		if msg.Method == "exit" {
+			if server.shutdown {
+				return 0, nil
+			}
+			return 1, nil
+		}
+
+		err = server.handle(msg)
+		if err != nil {
+			return 1, err
+		}
+	}
+}
+
+func (server *Server) handle(msg *message) error {
+	result, responseErr := server.dispatch(msg)
+
+	// notifications do not get a response
+	if msg.ID == nil {
+		return nil
+	}
+
+	response := &message{
+		ID:     msg.ID,
+		Result: result,
+		Error:  responseErr,
+	}
+	if responseErr == nil && result == nil {
+		response.Result = json.RawMessage("null")
+	}
+
+	return writeMessage(server.writer, response)
+}
+
+func (server *Server) dispatch(msg *message) (any, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   1, // the whole document is sent on every change
+				"definitionProvider": true,
+				"hoverProvider":      true,
+				"completionProvider": map[string]any{
+					"triggerCharacters": []string{"."},
+				},
+				"documentSymbolProvider": true,
+			},
+			"serverInfo": map[string]any{
+				"name":    "gal",
+				"version": genalphatypes.Version,
+			},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		server.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		path := normalize(uriToPath(params.TextDocument.URI))
+		server.documents[path] = params.TextDocument.Text
+		return nil, server.publishDiagnostics(path)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		path := normalize(uriToPath(params.TextDocument.URI))
+		for _, change := range params.ContentChanges {
+			server.documents[path] = change.Text
+		}
+		return nil, server.publishDiagnostics(path)
+	case "textDocument/didSave":
+		var params documentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		return nil, server.publishDiagnostics(normalize(uriToPath(params.TextDocument.URI)))
+	case "textDocument/didClose":
+		var params documentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		delete(server.documents, normalize(uriToPath(params.TextDocument.URI)))
+		return nil, nil
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		return server.definition(params), nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		return server.hover(params), nil
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		return server.completion(params), nil
+	case "textDocument/documentSymbol":
+		var params documentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+
+		return server.documentSymbols(params), nil
+	}
+
+	if msg.ID == nil || strings.HasPrefix(msg.Method, "$/") {
+		return nil, nil
+	}
+
+	return nil, &responseError{
+		Code:    errorMethodNotFound,
+		Message: "method " + msg.Method + " is not supported",
+	}
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{
+		Code:    errorInvalidParams,
+		Message: err.Error(),
+	}
+}
+
+// the open document or the file on disk, empty if neither exists
+func (server *Server) source(path string) string {
+	source, ok := server.documents[path]
+	if ok {
+		return source
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	return string(contents)
+}
+
+// the file the way the checker and the interpreter name it
+func normalize(path string) string {
+	return interpreter.LoadName(path)
+}
+
+// the symbols of the file and every file it imports
+func (server *Server) program(path string) []fileSymbols {
+	program := []fileSymbols{}
+	seen := map[string]bool{}
+
+	var load func(filename string)
+	load = func(filename string) {
+		if seen[filename] {
+			return
+		}
+		seen[filename] = true
+
+		index := indexFile(filename, server.source(filename))
+		program = append(program, index)
+		for _, imported := range index.imports {
+			importedFilename, err := interpreter.ResolveImport(filename, imported)
+			if err == nil {
+				load(importedFilename)
+			}
+		}
+	}
+	load(normalize(path))
+
+	return program
+}
+
+var lineNumber = regexp.MustCompile(`at line (\d+)`)
+
+func (server *Server) publishDiagnostics(path string) *responseError {
+	source := server.documents[path]
+	lines := strings.Split(source, "\n")
+	diagnostics := []diagnostic{}
+
+	add := func(line int, message string) {
+		if line < 0 || line >= len(lines) {
+			line = 0
+		}
+
+		diagnostics = append(diagnostics, diagnostic{
+			Range: textRange{
+				Start: position{Line: line},
+				End:   position{Line: line, Character: len(strings.TrimRight(lines[line], "\r"))},
+			},
+			Severity: severityError,
+			Source:   "gal",
+			Message:  message,
+		})
+	}
+
+	syntaxError := parse(source)
+	if syntaxError != "" {
+		// only the lexer knows the line of its errors
+		line := 0
+		match := lineNumber.FindStringSubmatch(syntaxError)
+		if match != nil {
+			line, _ = strconv.Atoi(match[1])
+			line--
+		}
+
+		add(line, syntaxError)
+	} else {
+		problems := checker.Check(path, server.modules, checker.Options{
+			Sources: server.documents,
+			Library: true,
+		})
+		for _, problem := range problems {
+			if problem.Filename == path {
+				add(problem.Line-1, problem.Message)
+			}
+		}
+	}
+
+	err := writeMessage(server.writer, &message{
+		Method: "textDocument/publishDiagnostics",
+		Params: mustMarshal(publishDiagnosticsParams{
+			URI:         pathToURI(path),
+			Diagnostics: diagnostics,
+		}),
+	})
+	if err != nil {
+		return &responseError{Message: err.Error()}
+	}
+
+	return nil
+}
+
+// returns the lexer or parser error, empty if the source parses
+func parse(source string) (syntaxError string) {
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxError = fmt.Sprint(r)
+		}
+	}()
+
+	parser.Parse(lexer.Lex(source))
+	return ""
+}
+
+func mustMarshal(value any) json.RawMessage {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
+
+// the token under the cursor and the declaration it refers to
+func (server *Server) resolve(params textDocumentPositionParams) (*genalphatypes.Token, *symbol) {
+	path := normalize(uriToPath(params.TextDocument.URI))
+	program := server.program(path)
+	current := program[0]
+
+	token := current.tokenAt(params.Position)
+	if token == nil || token.Type != genalphatypes.TokenTypeIdentifier {
+		return token, nil
+	}
+
+	return token, lookup(program, path, current.functionAt(token.Line), token.Value)
+}
+
+func (server *Server) definition(params textDocumentPositionParams) any {
+	_, declaration := server.resolve(params)
+	if declaration == nil {
+		return nil
+	}
+
+	return declaration.location()
+}
+
+func (server *Server) hover(params textDocumentPositionParams) any {
+	token, declaration := server.resolve(params)
+	if token == nil {
+		return nil
+	}
+
+	var contents string
+	switch {
+	case declaration != nil && declaration.isFunction:
+		contents = "```gal\n" + declaration.signature() + "\n```\n" + filepath.Base(declaration.filename) + ":" + strconv.Itoa(declaration.line+1)
+	case declaration != nil && declaration.function != "":
+		contents = "```gal\n" + string(genalphatypes.KeywordVar) + " " + declaration.name + "\n```\nin " + declaration.function
+	case declaration != nil:
+		contents = "```gal\n" + string(genalphatypes.KeywordVar) + " " + declaration.name + "\n```\n" + filepath.Base(declaration.filename) + ":" + strconv.Itoa(declaration.line+1)
+	case server.isNative(token.Value):
+		contents = "```gal\n" + token.Value + "\n```\nnative function"
+	default:
+		return nil
+	}
+
+	return hover{
+		Contents: markupContent{
+			Kind:  "markdown",
+			Value: contents,
+		},
+		Range: textRange{
+			Start: position{Line: token.Line, Character: token.Column},
+			End:   position{Line: token.Line, Character: token.Column + len(token.Value)},
+		},
+	}
+}
+
+func (server *Server) isNative(name string) bool {
+	i := strings.LastIndex(name, ".")
+	if i == -1 {
+		return false
+	}
+
+	for _, module := range server.modules {
+		if module.Name == name[:i] && module.Functions[name[i+1:]] != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// completes native functions, the functions of the program, the variables
+// the function at the cursor can see and keywords
+func (server *Server) completion(params textDocumentPositionParams) any {
+	path := normalize(uriToPath(params.TextDocument.URI))
+	program := server.program(path)
+	function := program[0].functionAt(params.Position.Line)
+
+	items := []completionItem{}
+	seen := map[string]bool{}
+	add := func(item completionItem) {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+
+	for _, index := range program {
+		for _, symbol := range index.symbols {
+			if symbol.isFunction {
+				add(completionItem{
+					Label:  symbol.name,
+					Kind:   completionKindFunction,
+					Detail: symbol.signature(),
+				})
+				continue
+			}
+
+			if visible(program, path, function, symbol) {
+				add(completionItem{
+					Label: symbol.name,
+					Kind:  completionKindVariable,
+				})
+			}
+		}
+	}
+
+	names := []string{}
+	for _, module := range server.modules {
+		for name := range module.Functions {
+			names = append(names, module.Name+"."+name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(completionItem{
+			Label:  name,
+			Kind:   completionKindFunction,
+			Detail: "native function",
+		})
+	}
+
+	for _, keyword := range genalphatypes.Keywords {
+		add(completionItem{
+			Label: keyword,
+			Kind:  completionKindKeyword,
+		})
+	}
+
+	return items
+}
+
+// the variable is what its name refers to in the function
+func visible(program []fileSymbols, path string, function string, variable symbol) bool {
+	declaration := lookup(program, path, function, variable.name)
+	return declaration != nil && !declaration.isFunction && declaration.filename == variable.filename && declaration.line == variable.line && declaration.column == variable.column
+}
+
+func (server *Server) documentSymbols(params documentParams) any {
+	path := normalize(uriToPath(params.TextDocument.URI))
+	index := indexFile(path, server.source(path))
+
+	symbols := []documentSymbol{}
+	functions := map[string]int{}
+	for _, symbol := range index.symbols {
+		selection := symbol.location().Range
+		if symbol.isFunction {
+			symbols = append(symbols, documentSymbol{
+				Name:   symbol.name,
+				Detail: symbol.signature(),
+				Kind:   symbolKindFunction,
+				Range: textRange{
+					Start: position{Line: symbol.line},
+					End:   position{Line: symbol.endLine, Character: len(string(genalphatypes.KeywordEnd))},
+				},
+				SelectionRange: selection,
+			})
+			functions[symbol.name] = len(symbols) - 1
+			continue
+		}
+
+		variable := documentSymbol{
+			Name:           symbol.name,
+			Kind:           symbolKindVariable,
+			Range:          selection,
+			SelectionRange: selection,
+		}
+
+		parent, ok := functions[symbol.function]
+		if symbol.function == "" || !ok {
+			symbols = append(symbols, variable)
+			continue
+		}
+
+		// a variable is listed once per function even if it is declared again
+		duplicate := false
+		for _, child := range symbols[parent].Children {
+			duplicate = duplicate || child.Name == symbol.name
+		}
+		if !duplicate {
+			symbols[parent].Children = append(symbols[parent].Children, variable)
+		}
+	}
+
+	return symbols
+}
@@ -0,0 +1,266 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bobik.squidwock.com/root/gal/genalpha/interpreter"
+)
+
+const mainSource = `lowkey main{}
+    fax greeting = fire greet("world")
+    fire std.println(greeting)
+end
+
+lowkey greet{name}
+    rizzult "hi " + name
+end
+`
+
+// a client that sends its requests up front and reads what the server wrote
+// once it exits
+type client struct {
+	t     *testing.T
+	input bytes.Buffer
+	next  int
+}
+
+func (client *client) request(method string, params any) json.RawMessage {
+	client.next++
+	id := json.RawMessage(mustMarshal(client.next))
+	client.send(&message{ID: &id, Method: method, Params: mustMarshal(params)})
+	return id
+}
+
+func (client *client) notify(method string, params any) {
+	client.send(&message{Method: method, Params: mustMarshal(params)})
+}
+
+func (client *client) send(msg *message) {
+	if err := writeMessage(&client.input, msg); err != nil {
+		client.t.Fatal(err)
+	}
+}
+
+// runs the server over the requests and returns the responses by id and the
+// notifications in the order the server sent them
+func (client *client) serve() (map[string]json.RawMessage, []*message) {
+	client.request("shutdown", nil)
+	client.notify("exit", nil)
+
+	var output bytes.Buffer
+	code, err := NewServer(&client.input, &output, interpreter.DefaultModules()).Serve()
+	if err != nil || code != 0 {
+		client.t.Fatalf("server exited with %d: %v", code, err)
+	}
+
+	responses := map[string]json.RawMessage{}
+	notifications := []*message{}
+	reader := bufio.NewReader(&output)
+	for {
+		msg, err := readMessage(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			client.t.Fatal(err)
+		}
+
+		if msg.ID == nil {
+			notifications = append(notifications, msg)
+			continue
+		}
+		if msg.Error != nil {
+			client.t.Fatalf("request %s failed: %+v", *msg.ID, msg.Error)
+		}
+		responses[string(*msg.ID)] = mustMarshal(msg.Result)
+	}
+
+	return responses, notifications
+}
+
+func decode[T any](t *testing.T, data json.RawMessage) T {
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatalf("can not decode %s: %v", data, err)
+	}
+	return value
+}
+
+func at(uri string, line int, character int) textDocumentPositionParams {
+	return textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Position:     position{Line: line, Character: character},
+	}
+}
+
+func open(client *client, uri string, text string) {
+	client.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "gal", "version": 1, "text": text},
+	})
+}
+
+func TestServer(t *testing.T) {
+	uri := pathToURI(filepath.Join(t.TempDir(), "main.gal"))
+
+	client := &client{t: t}
+	initialize := client.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	client.notify("initialized", map[string]any{})
+	open(client, uri, mainSource)
+	definition := client.request("textDocument/definition", at(uri, 1, 26))
+	hoverFunction := client.request("textDocument/hover", at(uri, 1, 26))
+	hoverVariable := client.request("textDocument/hover", at(uri, 2, 22))
+	hoverNative := client.request("textDocument/hover", at(uri, 2, 12))
+	completion := client.request("textDocument/completion", at(uri, 2, 4))
+	responses, notifications := client.serve()
+
+	capabilities := decode[struct {
+		Capabilities struct {
+			DefinitionProvider bool `json:"definitionProvider"`
+			HoverProvider      bool `json:"hoverProvider"`
+		} `json:"capabilities"`
+	}](t, responses[string(initialize)]).Capabilities
+	if !capabilities.DefinitionProvider || !capabilities.HoverProvider {
+		t.Errorf("initialize did not announce definition and hover: %+v", capabilities)
+	}
+
+	if len(notifications) != 1 || notifications[0].Method != "textDocument/publishDiagnostics" {
+		t.Fatalf("expected the diagnostics of the opened document, got %+v", notifications)
+	}
+	if diagnostics := decode[publishDiagnosticsParams](t, notifications[0].Params); diagnostics.URI != uri || len(diagnostics.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics for %s, got %+v", uri, diagnostics)
+	}
+
+	// greet is declared on line 5, after lowkey
+	if got := decode[location](t, responses[string(definition)]); got.URI != uri || got.Range.Start != (position{Line: 5, Character: 7}) {
+		t.Errorf("definition of greet is %+v", got)
+	}
+
+	for id, want := range map[string]string{
+		string(hoverFunction): "greet{name}",
+		string(hoverVariable): "fax greeting",
+		string(hoverNative):   "native function",
+	} {
+		if got := decode[hover](t, responses[id]).Contents.Value; !strings.Contains(got, want) {
+			t.Errorf("hover %s is %q, expected it to contain %q", id, got, want)
+		}
+	}
+
+	labels := map[string]int{}
+	for _, item := range decode[[]completionItem](t, responses[string(completion)]) {
+		labels[item.Label] = item.Kind
+	}
+	for label, kind := range map[string]int{
+		"greet":       completionKindFunction,
+		"greeting":    completionKindVariable,
+		"std.println": completionKindFunction,
+		"lowkey":      completionKindKeyword,
+	} {
+		if labels[label] != kind {
+			t.Errorf("completion %s has kind %d, expected %d", label, labels[label], kind)
+		}
+	}
+	if _, ok := labels["name"]; ok {
+		t.Error("completion in main offers the parameter of greet")
+	}
+}
+
+func TestServerReportsSyntaxErrors(t *testing.T) {
+	uri := pathToURI(filepath.Join(t.TempDir(), "broken.gal"))
+
+	client := &client{t: t}
+	client.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	open(client, uri, "lowkey main{}\n    fax x = \"open\nend\n")
+	_, notifications := client.serve()
+
+	if len(notifications) != 1 {
+		t.Fatalf("expected one notification, got %+v", notifications)
+	}
+	diagnostics := decode[publishDiagnosticsParams](t, notifications[0].Params).Diagnostics
+	if len(diagnostics) != 1 || diagnostics[0].Severity != severityError {
+		t.Errorf("expected one error, got %+v", diagnostics)
+	}
+}
+
+func TestServerRejectsUnknownRequests(t *testing.T) {
+	client := &client{t: t}
+	client.request("workspace/unknown", map[string]any{})
+	client.request("shutdown", nil)
+	client.notify("exit", nil)
+
+	var output bytes.Buffer
+	code, err := NewServer(&client.input, &output, nil).Serve()
+	if err != nil || code != 0 {
+		t.Fatalf("server exited with %d: %v", code, err)
+	}
+
+	msg, err := readMessage(bufio.NewReader(&output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Error == nil || msg.Error.Code != errorMethodNotFound {
+		t.Errorf("expected method not found, got %+v", msg)
+	}
+}
+
+// a body that is not JSON is answered with a parse error and the server goes on
+func TestServerAnswersInvalidJSON(t *testing.T) {
+	client := &client{t: t}
+	body := "{not json"
+	fmt.Fprintf(&client.input, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	initialize := client.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	client.request("shutdown", nil)
+	client.notify("exit", nil)
+
+	var output bytes.Buffer
+	code, err := NewServer(&client.input, &output, nil).Serve()
+	if err != nil || code != 0 {
+		t.Fatalf("server exited with %d: %v", code, err)
+	}
+
+	reader := bufio.NewReader(&output)
+	msg, err := readMessage(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != nil || msg.Error == nil || msg.Error.Code != errorParse {
+		t.Errorf("expected a parse error without an id, got %+v", msg)
+	}
+
+	msg, err = readMessage(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID == nil || string(*msg.ID) != string(initialize) || msg.Error != nil {
+		t.Errorf("expected the response to initialize, got %+v", msg)
+	}
+}
+
+func TestURIs(t *testing.T) {
+	for _, test := range []struct {
+		uri  string
+		path string
+	}{
+		{"file:///home/me/main.gal", "/home/me/main.gal"},
+		{"file:///home/me/my%20files/main.gal", "/home/me/my files/main.gal"},
+		{"file:///C:/me/main.gal", filepath.FromSlash("C:/me/main.gal")},
+	} {
+		if path := uriToPath(test.uri); path != test.path {
+			t.Errorf("uriToPath(%q) = %q, want %q", test.uri, path, test.path)
+		}
+		if uri := pathToURI(test.path); uri != test.uri {
+			t.Errorf("pathToURI(%q) = %q, want %q", test.path, uri, test.uri)
+		}
+	}
+
+	// editors such as vs code escape the colon after the drive letter
+	if path := uriToPath("file:///c%3A/me/main.gal"); path != filepath.FromSlash("c:/me/main.gal") {
+		t.Errorf("got %q", path)
+	}
+}
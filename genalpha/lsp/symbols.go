@@ -0,0 +1,219 @@
+package lsp
+
+import (
+	"strings"
+
+	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
+	"bobik.squidwock.com/root/gal/genalpha/lexer"
+)
+
+// symbols are found in the tokens instead of the syntax tree, tokens know their
+// column and a file that does not parse yet still has most of its symbols
+
+type symbol struct {
+	name     string
+	filename string
+	line     int // counting from 0
+	column   int
+
+	function string // the function a variable is declared in, empty on the top level
+
+	// only set for functions
+	isFunction bool
+	args       []string
+	endLine    int
+}
+
+type fileSymbols struct {
+	filename string
+	tokens   []genalphatypes.Token
+	symbols  []symbol
+	imports  []string // the files as written after gyat
+}
+
+// symbols of the file, the tokens are empty when the file does not lex
+func indexFile(filename string, source string) (index fileSymbols) {
+	index.filename = filename
+	defer func() {
+		if r := recover(); r != nil {
+			index.tokens = nil
+		}
+	}()
+
+	for _, token := range lexer.Lex(source) {
+		if token.Type != genalphatypes.TokenTypeWhitespace && token.Type != genalphatypes.TokenTypeComment {
+			index.tokens = append(index.tokens, token)
+		}
+	}
+
+	tokens := index.tokens
+	depth := 0
+	function := -1 // index of the function being declared in symbols
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Type != genalphatypes.TokenTypeKeyword {
+			continue
+		}
+
+		next := genalphatypes.Token{Type: genalphatypes.TokenTypeUnknown}
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+
+		switch genalphatypes.Keyword(token.Value) {
+		case genalphatypes.KeywordFunc:
+			if depth != 0 || next.Type != genalphatypes.TokenTypeIdentifier {
+				continue
+			}
+
+			declaration := symbol{
+				name:       next.Value,
+				filename:   filename,
+				line:       next.Line,
+				column:     next.Column,
+				isFunction: true,
+				endLine:    next.Line,
+			}
+
+			// lowkey name{a b}
+			arguments := []symbol{}
+			for i += 2; i < len(tokens) && tokens[i].Value != "}"; i++ {
+				if tokens[i].Type == genalphatypes.TokenTypeIdentifier {
+					declaration.args = append(declaration.args, tokens[i].Value)
+					arguments = append(arguments, symbol{
+						name:     tokens[i].Value,
+						filename: filename,
+						line:     tokens[i].Line,
+						column:   tokens[i].Column,
+						function: declaration.name,
+					})
+				}
+			}
+
+			index.symbols = append(index.symbols, declaration)
+			function = len(index.symbols) - 1
+			index.symbols = append(index.symbols, arguments...)
+			depth = 1
+		case genalphatypes.KeywordIf, genalphatypes.KeywordWhile, genalphatypes.KeywordTry:
+			depth++
+		case genalphatypes.KeywordEnd:
+			depth--
+			if depth <= 0 {
+				depth = 0
+				if function != -1 {
+					index.symbols[function].endLine = token.Line
+					function = -1
+				}
+			}
+		case genalphatypes.KeywordVar, genalphatypes.KeywordCatch:
+			if next.Type != genalphatypes.TokenTypeIdentifier || next.Line != token.Line {
+				continue
+			}
+
+			variable := symbol{
+				name:     next.Value,
+				filename: filename,
+				line:     next.Line,
+				column:   next.Column,
+			}
+			if function != -1 {
+				variable.function = index.symbols[function].name
+			}
+			index.symbols = append(index.symbols, variable)
+		case genalphatypes.KeywordImport:
+			if next.Type == genalphatypes.TokenTypeString {
+				index.imports = append(index.imports, next.Value)
+			}
+		}
+	}
+
+	// a function that is still open at the end of the file goes until the end
+	if function != -1 && len(tokens) != 0 {
+		index.symbols[function].endLine = tokens[len(tokens)-1].Line
+	}
+
+	return index
+}
+
+// the function declared around the line, empty on the top level
+func (index fileSymbols) functionAt(line int) string {
+	for _, symbol := range index.symbols {
+		if symbol.isFunction && symbol.line <= line && line <= symbol.endLine {
+			return symbol.name
+		}
+	}
+
+	return ""
+}
+
+// the identifier or keyword at the position, nil if there is none
+func (index fileSymbols) tokenAt(pos position) *genalphatypes.Token {
+	for i, token := range index.tokens {
+		if token.Line != pos.Line || token.Type == genalphatypes.TokenTypeNewline {
+			continue
+		}
+
+		if token.Column <= pos.Character && pos.Character <= token.Column+len(token.Value) {
+			if token.Type == genalphatypes.TokenTypeIdentifier || token.Type == genalphatypes.TokenTypeKeyword {
+				return &index.tokens[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// finds the declaration of name used in function, looking at the arguments and
+// variables of the function first, then the top level variables main and tests
+// see, then GLOBAL_ variables and functions of every file of the program
+func lookup(program []fileSymbols, filename string, function string, name string) *symbol {
+	for _, index := range program {
+		if index.filename != filename {
+			continue
+		}
+
+		for i, symbol := range index.symbols {
+			if !symbol.isFunction && symbol.function == function && symbol.name == name && function != "" {
+				return &index.symbols[i]
+			}
+		}
+	}
+
+	for _, index := range program {
+		for i, symbol := range index.symbols {
+			if symbol.isFunction && symbol.name == name {
+				return &index.symbols[i]
+			}
+		}
+	}
+
+	seesTopLevel := function == "" || function == "main" || strings.HasPrefix(function, "test_")
+	for _, index := range program {
+		for i, symbol := range index.symbols {
+			if symbol.isFunction || symbol.name != name {
+				continue
+			}
+
+			if symbol.function == "" && seesTopLevel || strings.HasPrefix(name, "GLOBAL_") {
+				return &index.symbols[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+func (symbol symbol) signature() string {
+	return string(genalphatypes.KeywordFunc) + " " + symbol.name + "{" + strings.Join(symbol.args, " ") + "}"
+}
+
+func (symbol symbol) location() location {
+	return location{
+		URI: pathToURI(symbol.filename),
+		Range: textRange{
+			Start: position{Line: symbol.line, Character: symbol.column},
+			End:   position{Line: symbol.line, Character: symbol.column + len(symbol.name)},
+		},
+	}
+}
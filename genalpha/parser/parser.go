@@ -2,7 +2,10 @@ package parser
 
 import (
 	"fmt"
+	"io"
 	"slices"
+	"strconv"
+	"strings"
 
 	genalphatypes "bobik.squidwock.com/root/gal/genalpha"
 )
@@ -22,6 +25,8 @@ const (
 	ProgramStateMemberAccess
 	ProgramStateMemberAssign
 	ProgramStateFunctionCallExpression
+	ProgramStateCatch
+	ProgramStateThrow
 )
 
 type ParserState struct {
@@ -31,7 +36,7 @@ type ParserState struct {
 	TokenIndex              int
 	OpenBrackets            int
 	OpenCurly               int
-	DeclarationCount        int  // loops, if, function
+	DeclarationCount        int  // loops, if, try, function
 	IsMemberAccessExpr      bool // only used for member access expression
 	IsCallExpr              bool
 	IsArgList               bool
@@ -49,6 +54,7 @@ type ParserState struct {
 	ASTNodeMemberAccess     genalphatypes.ASTNode
 	ASTNodeMemberAccessExpr genalphatypes.ASTNode
 	ASTNodeMemberAssign     genalphatypes.ASTNode
+	ASTNodeThrow            genalphatypes.ASTNode
 	ASTNodeParent           *genalphatypes.ASTNode   // for nested blocks
 	ASTNodeStack            []*genalphatypes.ASTNode // for nested blocks too
 }
@@ -106,6 +112,9 @@ func Parse(tokens []genalphatypes.Token) genalphatypes.ASTNode {
 		if token.Type == genalphatypes.TokenTypeKeyword && token.Value == string(genalphatypes.KeywordFunc) {
 			parserState.DeclarationCount++
 		}
+		if token.Type == genalphatypes.TokenTypeKeyword && token.Value == string(genalphatypes.KeywordTry) {
+			parserState.DeclarationCount++
+		}
 		if token.Type == genalphatypes.TokenTypeKeyword && token.Value == string(genalphatypes.KeywordEnd) {
 			parserState.DeclarationCount--
 		}
@@ -154,10 +163,18 @@ func Parse(tokens []genalphatypes.Token) genalphatypes.ASTNode {
 			continue
 		}
 
+		if parseTry(&parserState, token) {
+			continue
+		}
+
 		if parseIfWhile(&parserState, token) {
 			continue
 		}
 
+		if parseThrow(&parserState, token) {
+			continue
+		}
+
 		if parseReturn(&parserState, token) {
 			continue
 		}
@@ -240,7 +257,9 @@ func parseMemberAssignment(parserState *ParserState, token genalphatypes.Token)
 	if token.Type == genalphatypes.TokenTypePunctuation && token.Value == "[" && parserState.ProgramState == ProgramStateNormal {
 		parserState.ProgramState = ProgramStateMemberAssign
 		parserState.ASTNodeMemberAssign = genalphatypes.ASTNode{
-			Type: genalphatypes.ASTNodeTypeMemberAssignment,
+			Type:   genalphatypes.ASTNodeTypeMemberAssignment,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
 		}
 		resetExpression(parserState)
 		return true
@@ -297,7 +316,9 @@ func parseAssignment(parserState *ParserState, token genalphatypes.Token) bool {
 		parserState.ProgramState = ProgramStateVariableAssignment
 		resetExpression(parserState)
 		parserState.ASTNodeAssign = genalphatypes.ASTNode{
-			Type: genalphatypes.ASTNodeTypeVariableAssignment,
+			Type:   genalphatypes.ASTNodeTypeVariableAssignment,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
 		}
 		parserState.ASTNodeAssign.Children = append(parserState.ASTNodeAssign.Children, genalphatypes.ASTNode{
 			Type:  genalphatypes.ASTNodeTypeIdentifier,
@@ -335,7 +356,9 @@ func parseImport(parserState *ParserState, token genalphatypes.Token) bool {
 	if token.Type == genalphatypes.TokenTypeKeyword && token.Value == string(genalphatypes.KeywordImport) && parserState.ProgramState == ProgramStateNormal {
 		parserState.ProgramState = ProgramStateImport
 		parserState.ASTNodeImport = genalphatypes.ASTNode{
-			Type: genalphatypes.ASTNodeTypeImport,
+			Type:   genalphatypes.ASTNodeTypeImport,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
 		}
 		return true
 	}
@@ -366,7 +389,9 @@ func parseIfWhile(parserState *ParserState, token genalphatypes.Token) bool {
 			programState = ProgramStateWhile
 		}
 		parserState.ASTNodeParent = &genalphatypes.ASTNode{
-			Type: nodeType,
+			Type:   nodeType,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
 		}
 		resetExpression(parserState)
 		parserState.ProgramState = programState
@@ -402,12 +427,112 @@ func parseIfWhile(parserState *ParserState, token genalphatypes.Token) bool {
 	return false
 }
 
+// handles tryna blocks, sus and anyways are kept as marker nodes between the
+// statements of the try node and the block ends with end
+func parseTry(parserState *ParserState, token genalphatypes.Token) bool {
+	if token.Type == genalphatypes.TokenTypeKeyword && token.Value == string(genalphatypes.KeywordTry) && parserState.ProgramState == ProgramStateNormal {
+		parserState.ASTNodeStack = append(parserState.ASTNodeStack, parserState.ASTNodeParent)
+		parserState.ASTNodeParent = &genalphatypes.ASTNode{
+			Type:   genalphatypes.ASTNodeTypeTry,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
+		}
+		return true
+	}
+
+	if token.Type == genalphatypes.TokenTypeKeyword && token.Value == string(genalphatypes.KeywordCatch) && parserState.ProgramState == ProgramStateNormal {
+		if parserState.ASTNodeParent == nil || parserState.ASTNodeParent.Type != genalphatypes.ASTNodeTypeTry {
+			panic("PARSER: sus should be inside a tryna block")
+		}
+		for _, child := range parserState.ASTNodeParent.Children {
+			if child.Type == genalphatypes.ASTNodeTypeCatch || child.Type == genalphatypes.ASTNodeTypeFinally {
+				panic("PARSER: sus should come once and before anyways in a tryna block")
+			}
+		}
+
+		parserState.ASTNodeParent.Children = append(parserState.ASTNodeParent.Children, genalphatypes.ASTNode{
+			Type:   genalphatypes.ASTNodeTypeCatch,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
+		})
+		parserState.ProgramState = ProgramStateCatch
+		return true
+	}
+
+	if parserState.ProgramState == ProgramStateCatch {
+		if token.Type == genalphatypes.TokenTypeIdentifier {
+			parserState.ASTNodeParent.Children[len(parserState.ASTNodeParent.Children)-1].Value = token.Value
+			return true
+		}
+
+		if token.Type == genalphatypes.TokenTypeNewline {
+			parserState.ProgramState = ProgramStateNormal
+			return true
+		}
+
+		panic("PARSER: sus should be followed by the name of the error, such as 'sus err'")
+	}
+
+	if token.Type == genalphatypes.TokenTypeKeyword && token.Value == string(genalphatypes.KeywordFinally) && parserState.ProgramState == ProgramStateNormal {
+		if parserState.ASTNodeParent == nil || parserState.ASTNodeParent.Type != genalphatypes.ASTNodeTypeTry {
+			panic("PARSER: anyways should be inside a tryna block")
+		}
+		for _, child := range parserState.ASTNodeParent.Children {
+			if child.Type == genalphatypes.ASTNodeTypeFinally {
+				panic("PARSER: anyways should come only once in a tryna block")
+			}
+		}
+
+		parserState.ASTNodeParent.Children = append(parserState.ASTNodeParent.Children, genalphatypes.ASTNode{
+			Type:   genalphatypes.ASTNodeTypeFinally,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
+		})
+		return true
+	}
+
+	return false
+}
+
+// yeet "message"
+func parseThrow(parserState *ParserState, token genalphatypes.Token) bool {
+	if token.Type == genalphatypes.TokenTypeKeyword && token.Value == string(genalphatypes.KeywordThrow) && parserState.ProgramState == ProgramStateNormal {
+		parserState.ProgramState = ProgramStateThrow
+		resetExpression(parserState)
+		parserState.ASTNodeThrow = genalphatypes.ASTNode{
+			Type:   genalphatypes.ASTNodeTypeThrow,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
+		}
+		parserState.IsArgList = true
+		return true
+	}
+
+	if parserState.ProgramState == ProgramStateThrow {
+		if token.Type == genalphatypes.TokenTypeNewline {
+			fixExpression(&parserState.ASTNodeExpr)
+			parserState.ASTNodeThrow.Children = append(parserState.ASTNodeThrow.Children, parserState.ASTNodeExpr)
+			parserState.ProgramState = ProgramStateNormal
+			parserState.IsArgList = false
+			parserState.ASTNodeParent.Children = append(parserState.ASTNodeParent.Children, parserState.ASTNodeThrow)
+			return true
+		}
+
+		parseExpression(parserState, token, nil)
+		return true
+	}
+
+	return false
+}
+
 func parseReturn(parserState *ParserState, token genalphatypes.Token) bool {
 	if token.Type == genalphatypes.TokenTypeKeyword && token.Value == string(genalphatypes.KeywordReturn) && parserState.ProgramState == ProgramStateNormal {
 		parserState.ProgramState = ProgramStateReturn
 		resetExpression(parserState)
 		parserState.ASTNodeReturn = genalphatypes.ASTNode{
-			Type: genalphatypes.ASTNodeTypeReturn,
+			Type:   genalphatypes.ASTNodeTypeReturn,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
 		}
 		return true
 	}
@@ -433,7 +558,9 @@ func parseVariableDeclaration(parserState *ParserState, token genalphatypes.Toke
 		parserState.ProgramState = ProgramStateVariableDeclaration
 		resetExpression(parserState)
 		parserState.ASTNodeDecl = genalphatypes.ASTNode{
-			Type: genalphatypes.ASTNodeTypeVariableDeclaration,
+			Type:   genalphatypes.ASTNodeTypeVariableDeclaration,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
 		}
 		return true
 	}
@@ -477,7 +604,9 @@ func parseFunctionCallExpression(parserState *ParserState, token genalphatypes.T
 		parserState.PreviousState = parserState.ProgramState
 		parserState.ProgramState = ProgramStateFunctionCallExpression
 		parserState.ASTNodeCallExpr = genalphatypes.ASTNode{
-			Type: genalphatypes.ASTNodeTypeFunctionCall,
+			Type:   genalphatypes.ASTNodeTypeFunctionCall,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
 		}
 		parserState.ASTNodeExpr = genalphatypes.ASTNode{
 			Type: genalphatypes.ASTNodeTypeExpression,
@@ -542,7 +671,9 @@ func parseFunctionCall(parserState *ParserState, token genalphatypes.Token) bool
 	if token.Type == genalphatypes.TokenTypeKeyword && token.Value == string(genalphatypes.KeywordCall) && parserState.ProgramState == ProgramStateNormal {
 		parserState.ProgramState = ProgramStateFunctionCall
 		parserState.ASTNodeCall = genalphatypes.ASTNode{
-			Type: genalphatypes.ASTNodeTypeFunctionCall,
+			Type:   genalphatypes.ASTNodeTypeFunctionCall,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
 		}
 		resetExpression(parserState)
 		return true
@@ -609,7 +740,9 @@ func parseFunctionDeclarationLogic(parserState *ParserState, token genalphatypes
 
 		parserState.ProgramState = ProgramStateFunctionDeclaration
 		parserState.ASTNodeFunc = genalphatypes.ASTNode{
-			Type: genalphatypes.ASTNodeTypeFunctionDeclaration,
+			Type:   genalphatypes.ASTNodeTypeFunctionDeclaration,
+			Line:   token.Line + 1,
+			Column: token.Column + 1,
 		}
 		parserState.ASTNodeParent = &parserState.ASTNodeFunc
 		return true
@@ -911,12 +1044,42 @@ func orderOperations(expression *genalphatypes.ASTNode) {
 	}
 }
 
-func PrintAST(ast genalphatypes.ASTNode, level int) {
-	for i := 0; i < level; i++ {
-		fmt.Print("  ")
+// PrintAST writes the tree indented by level, one node per line with its type,
+// its value if it has one and its line and column if they are set
+func PrintAST(out io.Writer, ast genalphatypes.ASTNode, level int) {
+	fmt.Fprint(out, strings.Repeat("  ", level), ast.Type)
+	if ast.Value != "" {
+		fmt.Fprint(out, " ", strconv.Quote(ast.Value))
+	}
+	if ast.Line != 0 {
+		fmt.Fprintf(out, " %d:%d", ast.Line, ast.Column)
+	}
+	fmt.Fprintln(out)
+
+	for _, child := range ast.Children {
+		PrintAST(out, child, level+1)
+	}
+}
+
+// JSONNode is a node as written by gal ast -json
+type JSONNode struct {
+	Type     string     `json:"type"`
+	Value    string     `json:"value,omitempty"`
+	Line     int        `json:"line,omitempty"`
+	Column   int        `json:"column,omitempty"`
+	Children []JSONNode `json:"children,omitempty"`
+}
+
+func NewJSONNode(ast genalphatypes.ASTNode) JSONNode {
+	node := JSONNode{
+		Type:   ast.Type.String(),
+		Value:  ast.Value,
+		Line:   ast.Line,
+		Column: ast.Column,
 	}
-	fmt.Printf("%d: %s\n", ast.Type, ast.Value)
 	for _, child := range ast.Children {
-		PrintAST(child, level+1)
+		node.Children = append(node.Children, NewJSONNode(child))
 	}
+
+	return node
 }
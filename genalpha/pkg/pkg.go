@@ -3,6 +3,8 @@ package pkg
 import (
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
 	"runtime"
 	"strings"
 
@@ -53,15 +55,19 @@ func GetGalDirectory() string {
 		}
 	}
 
-	return userdir + "/.gal/"
+	return filepath.Join(userdir, ".gal")
 }
 
 func GetInstalledPackagesDirectory() string {
-	return GetGalDirectory() + "packages/"
+	return filepath.Join(GetGalDirectory(), "packages")
+}
+
+func GetCacheDirectory() string {
+	return filepath.Join(GetGalDirectory(), "cache")
 }
 
 func GetPackagePath(name string) string {
-	return GetInstalledPackagesDirectory() + name + "/"
+	return filepath.Join(GetInstalledPackagesDirectory(), name)
 }
 
 func ParsePackage(filename string) (Package, error) {
@@ -86,25 +92,25 @@ func BuildPackage(pkg Package) (string, error) {
 	}
 
 	// add all executables to path
-	execPath := GetGalDirectory() + "executables/"
+	execPath := filepath.Join(GetGalDirectory(), "executables")
 
 	for _, exe := range pkg.Executables {
 		if runtime.GOOS == "windows" {
 			// add a .bat file to the Package directory
-			batPath := execPath + exe.Name + ".bat"
+			batPath := filepath.Join(execPath, exe.Name+".bat")
 			batContents := `
 @echo off
-set EXECUTABLE_PATH="` + path + exe.Path + `"
+set EXECUTABLE_PATH="` + filepath.Join(path, exe.Path) + `"
 gal.exe run %EXECUTABLE_PATH% %*
 `
 			utils.WriteContents(batPath, batContents)
 		} else {
 			// create a executable file
-			filePath := execPath + exe.Name
+			filePath := filepath.Join(execPath, exe.Name)
 			fileContents := `
 #!/bin/bash
 
-export EXECUTABLE_PATH=` + path + exe.Path + `
+export EXECUTABLE_PATH=` + filepath.Join(path, exe.Path) + `
 gal run $EXECUTABLE_PATH $@
 `
 			utils.WriteContents(filePath, fileContents)
@@ -129,12 +135,12 @@ gal run $EXECUTABLE_PATH $@
 
 func InstallPackage(name string) error {
 	if strings.HasPrefix(name, ".") {
-		pack, err := ParsePackage(name + "/package.yml")
+		pack, err := ParsePackage(filepath.Join(name, "package.yml"))
 		if err != nil {
 			return err
 		}
 
-		err = utils.CopyDir(name, GetInstalledPackagesDirectory()+pack.Name)
+		err = utils.CopyDir(name, filepath.Join(GetInstalledPackagesDirectory(), pack.Name))
 		if err != nil {
 			return err
 		}
@@ -145,7 +151,8 @@ func InstallPackage(name string) error {
 	}
 
 	if strings.HasPrefix(name, "git+") {
-		pkgName := strings.Split(name, "/")[len(strings.Split(name, "/"))-1]
+		// the last part of the url, urls always use /
+		pkgName := path.Base(strings.TrimSuffix(name, "/"))
 		UninstallPackage(pkgName)
 		path := GetPackagePath(pkgName)
 		// clone the repository
@@ -158,7 +165,7 @@ func InstallPackage(name string) error {
 			return err
 		}
 		// build the package
-		pack, err := ParsePackage(path + "package.yml")
+		pack, err := ParsePackage(filepath.Join(path, "package.yml"))
 		if err != nil {
 			return err
 		}
@@ -172,8 +179,6 @@ func InstallPackage(name string) error {
 	// build the package
 	// install the package
 	panic("not implemented")
-
-	return nil
 }
 
 func UninstallPackage(name string) error {
@@ -183,18 +188,18 @@ func UninstallPackage(name string) error {
 	}
 
 	// remove executables
-	pack, err := ParsePackage(path + "package.yml")
+	pack, err := ParsePackage(filepath.Join(path, "package.yml"))
 	if err != nil {
 		return err
 	}
 
 	for _, exe := range pack.Executables {
-		execPath := GetGalDirectory() + "executables/"
-		if os.Getenv("GOOS") == "windows" {
-			batPath := execPath + exe.Name + ".bat"
+		execPath := filepath.Join(GetGalDirectory(), "executables")
+		if runtime.GOOS == "windows" {
+			batPath := filepath.Join(execPath, exe.Name+".bat")
 			os.Remove(batPath)
 		} else {
-			filePath := execPath + exe.Name
+			filePath := filepath.Join(execPath, exe.Name)
 			os.Remove(filePath)
 		}
 	}
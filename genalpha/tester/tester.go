@@ -0,0 +1,188 @@
+package tester
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bobik.squidwock.com/root/gal/genalpha/cache"
+	"bobik.squidwock.com/root/gal/genalpha/interpreter"
+)
+
+// test files end with _test.gal and every function in them starting with test_ is a test
+const (
+	FileSuffix     = "_test.gal"
+	FunctionPrefix = "test_"
+)
+
+type Options struct {
+	Filter   *regexp.Regexp // only tests with a matching name are run, nil runs all of them
+	Bytecode bool
+	Coverage *interpreter.Coverage // collects the coverage of all tests, the test files are left out
+	Seed     uint64                // seeds the rand module of every test, 0 seeds it randomly
+}
+
+type Result struct {
+	Filename string
+	Name     string
+	Line     int
+	Failure  string // empty when the test passed
+	Stack    []interpreter.CallFrame
+}
+
+func (result Result) Passed() bool {
+	return result.Failure == ""
+}
+
+// FindFiles returns the test files at path, which is either a test file or a
+// directory that is searched recursively
+func FindFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files := []string{}
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), FileSuffix) {
+			files = append(files, file)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+// Run runs the tests of every file, each in its own interpreter, and writes a
+// line per test to out followed by a summary
+func Run(files []string, options Options, out io.Writer) (passed int, failed int) {
+	for _, file := range files {
+		tests, err := findTests(file)
+		if err != nil {
+			fmt.Fprintf(out, "FAIL %s\n    %s\n", file, err)
+			failed++
+			continue
+		}
+
+		for _, test := range tests {
+			if options.Filter != nil && !options.Filter.MatchString(test.Name) {
+				continue
+			}
+
+			result := runTest(file, test, options)
+			report(out, result)
+			if result.Passed() {
+				passed++
+			} else {
+				failed++
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "\n%d passed, %d failed\n", passed, failed)
+	return passed, failed
+}
+
+func report(out io.Writer, result Result) {
+	if result.Passed() {
+		fmt.Fprintf(out, "PASS %s (%s:%d)\n", result.Name, result.Filename, result.Line)
+		return
+	}
+
+	fmt.Fprintf(out, "FAIL %s (%s:%d)\n", result.Name, result.Filename, result.Line)
+	fmt.Fprintf(out, "    %s\n", result.Failure)
+	trace := interpreter.FormatStackTrace(result.Stack)
+	for _, line := range strings.Split(strings.TrimSuffix(trace, "\n"), "\n") {
+		if line != "" {
+			fmt.Fprintf(out, "    %s\n", line)
+		}
+	}
+}
+
+func newInterpreter() *interpreter.InterpreterState {
+	interpreterState := interpreter.NewInterpreter([]string{})
+	for _, module := range interpreter.DefaultModules() {
+		interpreterState.RegisterModule(module)
+	}
+
+	return interpreterState
+}
+
+// returns the test functions declared in the file itself sorted by line
+func findTests(file string) (tests []interpreter.Function, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	ast := cache.LoadAST(file)
+	interpreterState := newInterpreter()
+	interpreterState.Load(&ast, interpreter.LoadName(file))
+
+	for name, function := range interpreterState.Functions {
+		if strings.HasPrefix(name, FunctionPrefix) && function.Filename == interpreter.LoadName(file) {
+			tests = append(tests, function)
+		}
+	}
+
+	sort.Slice(tests, func(i, j int) bool {
+		return tests[i].Line < tests[j].Line
+	})
+
+	return tests, nil
+}
+
+func runTest(file string, test interpreter.Function, options Options) (result Result) {
+	result = Result{
+		Filename: interpreter.LoadName(file),
+		Name:     test.Name,
+		Line:     test.Line,
+	}
+
+	interpreterState := newInterpreter()
+	if options.Coverage != nil {
+		options.Coverage.Ignore(interpreter.LoadName(file))
+		interpreterState.Coverage = options.Coverage
+	}
+	if options.Seed != 0 {
+		interpreterState.Seed(options.Seed)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			result.Failure = fmt.Sprint(r)
+			result.Stack = interpreterState.StackTrace(r)
+		}
+	}()
+
+	ast := cache.LoadAST(file)
+	interpreterState.Load(&ast, interpreter.LoadName(file))
+
+	var code int
+	if options.Bytecode {
+		code = interpreterState.RunBytecodeFunction(test.Name)
+	} else {
+		code = interpreterState.RunFunction(test.Name)
+	}
+
+	if code != 0 {
+		result.Failure = "exited with code " + strconv.Itoa(code)
+	}
+
+	return result
+}
@@ -0,0 +1,171 @@
+package tester
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const mathTest = `gyat "helpers.gal"
+
+lowkey test_adds{}
+    fire test.assert_eq(1 + 2, 3)
+end
+
+lowkey test_fails{}
+    fire test.assert_eq(1 + 2, 4, "sum")
+end
+
+lowkey test_exits{}
+    rizzult 3
+end
+
+lowkey helper{}
+    fire test.fail()
+end
+`
+
+// test_ functions of imported files belong to the tests of those files
+const helpers = `lowkey test_imported{}
+    fire test.fail()
+end
+`
+
+// writes the files under a temporary directory, which is also the home
+// directory the parsed files are cached in
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("APPDATA", dir)
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestFindFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"math_test.gal":                    mathTest,
+		"helpers.gal":                      helpers,
+		filepath.Join("lib", "a_test.gal"): "",
+		"notes_test.txt":                   "",
+	})
+
+	files, err := FindFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0] != filepath.Join(dir, "lib", "a_test.gal") || files[1] != filepath.Join(dir, "math_test.gal") {
+		t.Errorf("got %v", files)
+	}
+
+	// a file is run even if it is not named like a test file
+	single := filepath.Join(dir, "helpers.gal")
+	if files, err := FindFiles(single); err != nil || len(files) != 1 || files[0] != single {
+		t.Errorf("got %v %v", files, err)
+	}
+
+	if _, err := FindFiles(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"math_test.gal": mathTest, "helpers.gal": helpers})
+	file := filepath.Join(dir, "math_test.gal")
+
+	for _, bytecode := range []bool{false, true} {
+		var out bytes.Buffer
+		passed, failed := Run([]string{file}, Options{Bytecode: bytecode}, &out)
+		if passed != 1 || failed != 2 {
+			t.Errorf("bytecode %v: %d passed, %d failed\n%s", bytecode, passed, failed, out.String())
+		}
+
+		want := []string{
+			"PASS test_adds (" + file + ":3)",
+			"FAIL test_fails (" + file + ":7)",
+			"    sum: expected 4, got 3",
+			"        at test_fails (" + file + ":8)",
+			"FAIL test_exits (" + file + ":11)",
+			"    exited with code 3",
+			"",
+			"1 passed, 2 failed",
+		}
+		if got := strings.TrimSuffix(out.String(), "\n"); got != strings.Join(want, "\n") {
+			t.Errorf("bytecode %v: got\n%s\nwant\n%s", bytecode, got, strings.Join(want, "\n"))
+		}
+	}
+}
+
+func TestRunFilter(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"math_test.gal": mathTest, "helpers.gal": helpers})
+
+	var out bytes.Buffer
+	passed, failed := Run([]string{filepath.Join(dir, "math_test.gal")}, Options{Filter: regexp.MustCompile("add")}, &out)
+	if passed != 1 || failed != 0 || strings.Contains(out.String(), "test_fails") {
+		t.Errorf("%d passed, %d failed\n%s", passed, failed, out.String())
+	}
+}
+
+// a file that can not be loaded counts as a failed test
+func TestRunBrokenFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"broken_test.gal": "lowkey test_broken{}\n    fire std.println(1\nend\n"})
+	file := filepath.Join(dir, "broken_test.gal")
+
+	var out bytes.Buffer
+	passed, failed := Run([]string{file}, Options{}, &out)
+	if passed != 0 || failed != 1 || !strings.HasPrefix(out.String(), "FAIL "+file+"\n") {
+		t.Errorf("%d passed, %d failed\n%s", passed, failed, out.String())
+	}
+}
+
+// the assertions check their arguments like the other native modules
+func TestAssertionArguments(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"assert_test.gal": `lowkey test_too_few{}
+    fire test.assert_eq(1)
+end
+
+lowkey test_message_not_a_string{}
+    fire test.assert(nay, 1)
+end
+
+lowkey test_name_not_a_string{}
+    fire test.assert_throws(1)
+end
+
+lowkey test_fail{}
+    fire test.fail()
+end
+
+lowkey test_fail_with_message{}
+    fire test.fail("not done")
+end
+`})
+
+	var out bytes.Buffer
+	Run([]string{filepath.Join(dir, "assert_test.gal")}, Options{}, &out)
+
+	for _, want := range []string{
+		"    test.assert_eq expects 2 or 3 arguments\n",
+		"    test.assert expects a string as argument 2, got 1\n",
+		"    test.assert_throws expects a string as argument 1, got 1\n",
+		"    failed\n",
+		"    not done: failed\n",
+		"0 passed, 5 failed\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in\n%s", want, out.String())
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package genalphatypes
 
+import "strconv"
+
 type ASTNodeType int
 
 const (
@@ -26,13 +28,61 @@ const (
 	ASTNodeTypeMemberAccess
 	ASTNodeTypeBlock
 	ASTNodeTypeArray
+	ASTNodeTypeTry
+	ASTNodeTypeCatch
+	ASTNodeTypeFinally
+	ASTNodeTypeThrow
+	ASTNodeTypeError
 	ASTNodeTypeUnknown
 )
 
+var astNodeTypeNames = []string{
+	"Program",
+	"Expression",
+	"FunctionDeclaration",
+	"FunctionCall",
+	"VariableDeclaration",
+	"VariableAssignment",
+	"If",
+	"Import",
+	"While",
+	"Return",
+	"Operator",
+	"BinaryOperation",
+	"UnaryOperation",
+	"Identifier",
+	"Number",
+	"String",
+	"Boolean",
+	"None",
+	"FunctionArgument",
+	"MemberAssignment",
+	"MemberAccess",
+	"Block",
+	"Array",
+	"Try",
+	"Catch",
+	"Finally",
+	"Throw",
+	"Error",
+	"Unknown",
+}
+
+// String returns the name of the constant without the ASTNodeType prefix
+func (nodeType ASTNodeType) String() string {
+	if nodeType < 0 || int(nodeType) >= len(astNodeTypeNames) {
+		return "ASTNodeType(" + strconv.Itoa(int(nodeType)) + ")"
+	}
+
+	return astNodeTypeNames[nodeType]
+}
+
 type ASTNode struct {
 	Type     ASTNodeType
 	Children []ASTNode
 	Value    string
+	Line     int // line counting from 1, only set on statements and function calls
+	Column   int // column of the first token counting from 1, set together with Line
 }
 
 type TokenType int
@@ -51,22 +101,49 @@ const (
 	TokenTypeUnknown
 )
 
+var tokenTypeNames = []string{
+	"Identifier",
+	"Number",
+	"String",
+	"Boolean",
+	"Keyword",
+	"Operator",
+	"Punctuation",
+	"Comment",
+	"Whitespace",
+	"Newline",
+	"Unknown",
+}
+
+// String returns the name of the constant without the TokenType prefix
+func (tokenType TokenType) String() string {
+	if tokenType < 0 || int(tokenType) >= len(tokenTypeNames) {
+		return "TokenType(" + strconv.Itoa(int(tokenType)) + ")"
+	}
+
+	return tokenTypeNames[tokenType]
+}
+
 type Keyword string
 
 const (
-	KeywordVar    Keyword = "fax"
-	KeywordIf     Keyword = "foreal"
-	KeywordIfYes  Keyword = "yeah"
-	KeywordIfNo   Keyword = "nah"
-	KeywordFunc   Keyword = "lowkey"
-	KeywordEnd    Keyword = "end"
-	KeywordCall   Keyword = "fire"
-	KeywordWhile  Keyword = "durin"
-	KeywordImport Keyword = "gyat"
-	KeywordReturn Keyword = "rizzult"
-	KeywordTrue   Keyword = "yay"
-	KeywordFalse  Keyword = "nay"
-	KeywordNone   Keyword = "nuthin"
+	KeywordVar     Keyword = "fax"
+	KeywordIf      Keyword = "foreal"
+	KeywordIfYes   Keyword = "yeah"
+	KeywordIfNo    Keyword = "nah"
+	KeywordFunc    Keyword = "lowkey"
+	KeywordEnd     Keyword = "end"
+	KeywordCall    Keyword = "fire"
+	KeywordWhile   Keyword = "durin"
+	KeywordImport  Keyword = "gyat"
+	KeywordReturn  Keyword = "rizzult"
+	KeywordTrue    Keyword = "yay"
+	KeywordFalse   Keyword = "nay"
+	KeywordNone    Keyword = "nuthin"
+	KeywordTry     Keyword = "tryna"
+	KeywordCatch   Keyword = "sus"
+	KeywordFinally Keyword = "anyways"
+	KeywordThrow   Keyword = "yeet"
 )
 
 var (
@@ -84,11 +161,16 @@ var (
 		string(KeywordTrue),
 		string(KeywordFalse),
 		string(KeywordNone),
+		string(KeywordTry),
+		string(KeywordCatch),
+		string(KeywordFinally),
+		string(KeywordThrow),
 	}
 )
 
 type Token struct {
-	Type  TokenType
-	Value string
-	Line  int
+	Type   TokenType
+	Value  string
+	Line   int // counting from 0
+	Column int // byte offset in the line counting from 0
 }
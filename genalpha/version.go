@@ -0,0 +1,4 @@
+package genalphatypes
+
+// Version of gal, cached syntax trees are only reused by the same version
+const Version = "0.1.0"
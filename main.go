@@ -2,55 +2,433 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime/debug"
 
+	"bobik.squidwock.com/root/gal/genalpha/cache"
+	"bobik.squidwock.com/root/gal/genalpha/checker"
+	"bobik.squidwock.com/root/gal/genalpha/debugger"
+	"bobik.squidwock.com/root/gal/genalpha/format"
 	"bobik.squidwock.com/root/gal/genalpha/interpreter"
 	"bobik.squidwock.com/root/gal/genalpha/lexer"
+	"bobik.squidwock.com/root/gal/genalpha/lsp"
 	"bobik.squidwock.com/root/gal/genalpha/parser"
 	"bobik.squidwock.com/root/gal/genalpha/pkg"
-	"bobik.squidwock.com/root/gal/genalpha/utils"
+	"bobik.squidwock.com/root/gal/genalpha/tester"
 	"github.com/google/subcommands"
 )
 
-type runCmd struct{}
+type runCmd struct {
+	bytecode   bool
+	goStack    bool
+	profile    string
+	profileTop int
+	coverage   string
+	trace      string
+	traceFunc  string
+	traceFile  string
+	seed       uint64
+}
+type testCmd struct {
+	run      string
+	bytecode bool
+	coverage string
+	seed     uint64
+}
+type checkCmd struct{}
+type fmtCmd struct {
+	check bool
+	write bool
+}
+type tokensCmd struct {
+	json bool
+}
+type astCmd struct {
+	json bool
+}
+type lspCmd struct{}
+type debugCmd struct{}
 type installCmd struct{}
 type uninstallCmd struct{}
 type buildCmd struct{}
 
 func (*runCmd) Name() string       { return "run" }
+func (*testCmd) Name() string      { return "test" }
+func (*checkCmd) Name() string     { return "check" }
+func (*fmtCmd) Name() string       { return "fmt" }
+func (*tokensCmd) Name() string    { return "tokens" }
+func (*astCmd) Name() string       { return "ast" }
+func (*lspCmd) Name() string       { return "lsp" }
+func (*debugCmd) Name() string     { return "debug" }
 func (*installCmd) Name() string   { return "install" }
 func (*uninstallCmd) Name() string { return "uninstall" }
 func (*buildCmd) Name() string     { return "build" }
 
 func (*runCmd) Synopsis() string       { return "Run the specified file" }
+func (*testCmd) Synopsis() string      { return "Run the tests in *_test.gal files" }
+func (*checkCmd) Synopsis() string     { return "Find mistakes in a program without running it" }
+func (*fmtCmd) Synopsis() string       { return "Format gal source files" }
+func (*tokensCmd) Synopsis() string    { return "Print the tokens the lexer makes of a file" }
+func (*astCmd) Synopsis() string       { return "Print the syntax tree the parser makes of a file" }
+func (*lspCmd) Synopsis() string       { return "Run the language server for editors over stdio" }
+func (*debugCmd) Synopsis() string     { return "Run the debug adapter for editors over stdio" }
 func (*installCmd) Synopsis() string   { return "Install the specified package" }
 func (*uninstallCmd) Synopsis() string { return "Uninstall the specified package" }
 func (*buildCmd) Synopsis() string     { return "Build a package" }
 
-func (*runCmd) Usage() string       { return "run <path>" }
+func (*runCmd) Usage() string       { return "run [flags] <path> [args...]" }
+func (*testCmd) Usage() string      { return "test [flags] [path]" }
+func (*checkCmd) Usage() string     { return "check <path...>" }
+func (*fmtCmd) Usage() string       { return "fmt [-check] [-write] [path...]" }
+func (*tokensCmd) Usage() string    { return "tokens [-json] <path>" }
+func (*astCmd) Usage() string       { return "ast [-json] <path>" }
+func (*lspCmd) Usage() string       { return "lsp" }
+func (*debugCmd) Usage() string     { return "debug" }
 func (*installCmd) Usage() string   { return "install <package>" }
 func (*uninstallCmd) Usage() string { return "uninstall <package>" }
 func (*buildCmd) Usage() string     { return "build <path>" }
 
-func (p *runCmd) SetFlags(f *flag.FlagSet)       {}
+func (p *runCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.bytecode, "bytecode", false, "compile to bytecode and run it on the virtual machine")
+	f.BoolVar(&p.goStack, "gostack", false, "print the go stack of the interpreter too when the program fails, for reporting interpreter bugs")
+	f.StringVar(&p.profile, "profile", "", "write a pprof profile of the time spent in every function and line to the file, and print the slowest ones")
+	f.IntVar(&p.profileTop, "profile-top", 10, "number of functions and lines printed by -profile")
+	f.StringVar(&p.coverage, "coverage", "", "print which part of the program ran and write lcov.info and index.html reports to the directory")
+	f.StringVar(&p.trace, "trace", "", "write every statement, call, return and assignment to the file as JSON lines, - writes to stderr")
+	f.StringVar(&p.traceFunc, "trace-func", "", "only trace in the functions whose name matches the regular expression")
+	f.StringVar(&p.traceFile, "trace-file", "", "only trace in the files whose path matches the regular expression")
+	f.Uint64Var(&p.seed, "seed", 0, "seed the rand module so it returns the same numbers on every run, 0 picks a random seed")
+}
+
+func (p *testCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.run, "run", "", "only run the tests whose name matches the regular expression")
+	f.BoolVar(&p.bytecode, "bytecode", false, "run the tests on the virtual machine")
+	f.StringVar(&p.coverage, "coverage", "", "print which part of the code outside the test files ran and write lcov.info and index.html reports to the directory")
+	f.Uint64Var(&p.seed, "seed", 0, "seed the rand module of every test, 0 picks a random seed")
+}
+
+func (p *checkCmd) SetFlags(f *flag.FlagSet) {}
+
+func (p *fmtCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.check, "check", false, "list the files that are not formatted and exit with status 1 if there are any")
+	f.BoolVar(&p.write, "write", false, "write the formatted source back to the files instead of printing it")
+}
+
+func (p *tokensCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.json, "json", false, "print a JSON array of the tokens")
+}
+
+func (p *astCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.json, "json", false, "print the tree as JSON")
+}
+
+func (p *lspCmd) SetFlags(f *flag.FlagSet)       {}
+func (p *debugCmd) SetFlags(f *flag.FlagSet)     {}
 func (p *installCmd) SetFlags(f *flag.FlagSet)   {}
 func (p *uninstallCmd) SetFlags(f *flag.FlagSet) {}
 func (p *buildCmd) SetFlags(f *flag.FlagSet)     {}
 
-func (p *runCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+func (p *runCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) (status subcommands.ExitStatus) {
 	if f.NArg() == 0 {
 		panic("missing path to file")
 	}
 	filename := f.Arg(0)
-	contents := utils.ReadContents(filename)
-	tokens := lexer.Lex(contents)
-	ast := parser.Parse(tokens)
+	ast := cache.LoadAST(filename)
+
+	interpreterState := interpreter.NewInterpreter(f.Args()[1:])
+	for _, module := range interpreter.DefaultModules() {
+		interpreterState.RegisterModule(module)
+	}
+	if p.seed != 0 {
+		interpreterState.Seed(p.seed)
+	}
+
+	// set before loading so the top level code of imported files is measured too,
+	// the report is printed after the error if the program fails
+	if p.profile != "" {
+		interpreterState.Profiler = interpreter.NewProfiler()
+		defer func() {
+			interpreterState.Profiler.Stop(interpreterState)
+			interpreterState.Profiler.WriteReport(os.Stderr, p.profileTop)
+
+			err := writeFile(p.profile, interpreterState.Profiler.WritePprof)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "could not write profile:", err)
+			}
+		}()
+	}
+
+	if p.coverage != "" {
+		interpreterState.Coverage = interpreter.NewCoverage()
+		defer writeCoverage(interpreterState.Coverage, p.coverage, os.Stderr)
+	}
+
+	if p.trace != "" {
+		interpreterState.Tracer = newTracer(p.trace, p.traceFunc, p.traceFile)
+		defer func() {
+			err := interpreterState.Tracer.Flush()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "could not write trace:", err)
+			}
+		}()
+	}
+
+	// uncaught gal errors are reported with the gal stack trace, anything else is
+	// an interpreter failure and is left to main unless the go stack was asked for
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		_, isRuntimeError := r.(*interpreter.RuntimeError)
+		if !isRuntimeError && !p.goStack {
+			panic(r)
+		}
+
+		fmt.Fprintln(os.Stderr, r)
+		fmt.Fprint(os.Stderr, interpreter.FormatStackTrace(interpreterState.StackTrace(r)))
+		if p.goStack {
+			fmt.Fprintf(os.Stderr, "\ngo stack:\n%s", debug.Stack())
+		}
+		status = subcommands.ExitFailure
+	}()
+
+	interpreterState.Load(&ast, interpreter.LoadName(filename))
+	if p.bytecode {
+		return subcommands.ExitStatus(interpreterState.RunBytecode())
+	}
+
+	return subcommands.ExitStatus(interpreterState.Run())
+}
+
+func (p *testCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	path := "."
+	if f.NArg() != 0 {
+		path = f.Arg(0)
+	}
+
+	options := tester.Options{
+		Bytecode: p.bytecode,
+		Seed:     p.seed,
+	}
+	if p.coverage != "" {
+		options.Coverage = interpreter.NewCoverage()
+	}
+	if p.run != "" {
+		filter, err := regexp.Compile(p.run)
+		if err != nil {
+			panic(err)
+		}
+		options.Filter = filter
+	}
+
+	files, err := tester.FindFiles(path)
+	if err != nil {
+		panic(err)
+	}
+	if len(files) == 0 {
+		fmt.Println("no test files in", path)
+		return subcommands.ExitSuccess
+	}
+
+	_, failed := tester.Run(files, options, os.Stdout)
+	if options.Coverage != nil {
+		fmt.Println()
+		writeCoverage(options.Coverage, p.coverage, os.Stdout)
+	}
+	if failed != 0 {
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// the file is left open, gal exits when the program is done
+func newTracer(filename string, functions string, files string) *interpreter.Tracer {
+	out := os.Stderr
+	if filename != "-" {
+		file, err := os.Create(filename)
+		if err != nil {
+			panic(err)
+		}
+		out = file
+	}
+
+	tracer := interpreter.NewTracer(out)
+	if functions != "" {
+		tracer.Functions = regexp.MustCompile(functions)
+	}
+	if files != "" {
+		tracer.Files = regexp.MustCompile(files)
+	}
+
+	return tracer
+}
+
+// prints the summary to out and writes the reports to the directory
+func writeCoverage(coverage *interpreter.Coverage, directory string, out *os.File) {
+	coverage.WriteSummary(out)
+
+	err := os.MkdirAll(directory, 0755)
+	if err == nil {
+		err = writeFile(filepath.Join(directory, "lcov.info"), coverage.WriteLCOV)
+	}
+	if err == nil {
+		err = writeFile(filepath.Join(directory, "index.html"), coverage.WriteHTML)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "could not write coverage:", err)
+	}
+}
+
+func writeFile(filename string, write func(out io.Writer) error) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	err = write(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func (p *checkCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() == 0 {
+		panic("missing path to file")
+	}
+
+	status := subcommands.ExitSuccess
+	for _, filename := range f.Args() {
+		for _, problem := range checker.Check(filename, interpreter.DefaultModules(), checker.Options{}) {
+			fmt.Println(problem)
+			status = subcommands.ExitFailure
+		}
+	}
+
+	return status
+}
+
+func (p *fmtCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	paths := f.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	status := subcommands.ExitSuccess
+	for _, path := range paths {
+		files, err := format.FindFiles(path)
+		if err != nil {
+			panic(err)
+		}
+
+		for _, file := range files {
+			contents, err := os.ReadFile(file)
+			if err != nil {
+				panic(err)
+			}
+
+			formatted, err := format.Format(string(contents))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", file, err)
+				status = subcommands.ExitFailure
+				continue
+			}
+
+			switch {
+			case p.check:
+				if formatted != string(contents) {
+					fmt.Println(file)
+					status = subcommands.ExitFailure
+				}
+			case p.write:
+				if formatted != string(contents) {
+					err = os.WriteFile(file, []byte(formatted), 0644)
+					if err != nil {
+						panic(err)
+					}
+				}
+			default:
+				fmt.Print(formatted)
+			}
+		}
+	}
+
+	return status
+}
+
+func (p *tokensCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	tokens := lexer.Lex(readSource(f))
+	if p.json {
+		printJSON(lexer.NewJSONTokens(tokens))
+		return subcommands.ExitSuccess
+	}
+
+	lexer.PrintTokens(os.Stdout, tokens)
+	return subcommands.ExitSuccess
+}
+
+func (p *astCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	ast := parser.Parse(lexer.Lex(readSource(f)))
+	if p.json {
+		printJSON(parser.NewJSONNode(ast))
+		return subcommands.ExitSuccess
+	}
+
+	parser.PrintAST(os.Stdout, ast, 0)
+	return subcommands.ExitSuccess
+}
+
+// the file is lexed and parsed again instead of taken from the cache so what
+// is printed is what the current lexer and parser make of it
+func readSource(f *flag.FlagSet) string {
+	if f.NArg() == 0 {
+		panic("missing path to file")
+	}
+
+	contents, err := os.ReadFile(f.Arg(0))
+	if err != nil {
+		panic(err)
+	}
+
+	return string(contents)
+}
+
+func printJSON(value any) {
+	encoded, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(string(encoded))
+}
+
+func (p *lspCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	server := lsp.NewServer(os.Stdin, os.Stdout, interpreter.DefaultModules())
+	code, err := server.Serve()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	return subcommands.ExitStatus(code)
+}
+
+func (p *debugCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	err := debugger.NewServer(os.Stdin, os.Stdout).Serve()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return subcommands.ExitFailure
+	}
 
-	contextDir := filepath.Dir(filename)
-	interpreter.Interpret(&ast, f.Args()[1:], contextDir+"/")
 	return subcommands.ExitSuccess
 }
 
@@ -105,10 +483,18 @@ func main() {
 				return
 			}
 			fmt.Println(r)
+			os.Exit(1)
 		}
 	}()
 
 	subcommands.Register(&runCmd{}, "")
+	subcommands.Register(&testCmd{}, "")
+	subcommands.Register(&checkCmd{}, "")
+	subcommands.Register(&fmtCmd{}, "")
+	subcommands.Register(&tokensCmd{}, "")
+	subcommands.Register(&astCmd{}, "")
+	subcommands.Register(&lspCmd{}, "")
+	subcommands.Register(&debugCmd{}, "")
 	subcommands.Register(&installCmd{}, "")
 	subcommands.Register(&uninstallCmd{}, "")
 	subcommands.Register(&buildCmd{}, "")